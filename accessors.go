@@ -148,3 +148,63 @@ func (this Document) GetDate(name string, defaultValue...time.Time) time.Time {
 	return v.AsDate()
 }
 
+func (this Document) GetLocalDate(name string, defaultValue...LocalDate) LocalDate {
+	v, ok := this.GetValue(name)
+	if !ok {
+		if len(defaultValue) >= 1 {
+			return defaultValue[0]
+		} else {
+			return LocalDate{}
+		}
+	}
+	return v.AsLocalDate()
+}
+
+func (this Document) GetLocalTime(name string, defaultValue...LocalTime) LocalTime {
+	v, ok := this.GetValue(name)
+	if !ok {
+		if len(defaultValue) >= 1 {
+			return defaultValue[0]
+		} else {
+			return LocalTime{}
+		}
+	}
+	return v.AsLocalTime()
+}
+
+func (this Document) GetLocalDateTime(name string, defaultValue...LocalDateTime) LocalDateTime {
+	v, ok := this.GetValue(name)
+	if !ok {
+		if len(defaultValue) >= 1 {
+			return defaultValue[0]
+		} else {
+			return LocalDateTime{}
+		}
+	}
+	return v.AsLocalDateTime()
+}
+
+func (this Document) GetDuration(name string, defaultValue...time.Duration) time.Duration {
+	v, ok := this.GetValue(name)
+	if !ok {
+		if len(defaultValue) >= 1 {
+			return defaultValue[0]
+		} else {
+			return 0
+		}
+	}
+	return v.AsDuration()
+}
+
+func (this Document) GetByteSize(name string, defaultValue...ByteSize) ByteSize {
+	v, ok := this.GetValue(name)
+	if !ok {
+		if len(defaultValue) >= 1 {
+			return defaultValue[0]
+		} else {
+			return 0
+		}
+	}
+	return v.AsByteSize()
+}
+
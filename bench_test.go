@@ -0,0 +1,99 @@
+package toml
+
+import (
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Goals:
+//
+//	BenchmarkParseSmall    >= 150 MB/s
+//	BenchmarkParseLarge    >= 50 MB/s, <= 3 allocs/key, <= 400 B/key
+//	BenchmarkParseNested   >= 20 MB/s, <= 40 allocs/key
+//
+// allocs/key and B/key are the number of allocations and of bytes allocated,
+// divided by the number of keys and sections in the document. The nested
+// document has dozens of arrays per key.
+//
+// Measured with Go 1.27 on the reference machine, a single-core Intel Xeon
+// virtual machine, median of 10 runs alternated with runs of the parser before
+// nodes and elements were allocated in slabs, in parentheses:
+//
+//	BenchmarkParseSmall    111 MB/s (68), 1.3 allocs/key (4.5), 556 B/key (428)
+//	BenchmarkParseLarge    40 MB/s (21), 0.34 allocs/key (2.8), 283 B/key (381)
+//	BenchmarkParseNested   17 MB/s (12), 0.52 allocs/key (27), 3016 B/key (4340)
+//
+// The allocation goals are met, the throughput goals are not: the fastest runs
+// reach 142, 50 and 21 MB/s, but the runs vary by up to 40% on this machine and
+// the medians stay below. Most of the remaining time goes to copying the values
+// and to the garbage collector. Check for regressions with:
+//
+//	go test -run NONE -bench Parse -benchmem -count 5
+
+func benchmarkParse(b *testing.B, content string) {
+	doc := Parser{}.Parse(content)
+	keys := 0
+	doc.Walk(func(path []string, node *Node, value *Value) error {
+		if value == nil || path[len(path) - 1] == node.name { keys++ }
+		return nil
+	})
+
+	var before, after runtime.MemStats
+	b.SetBytes(int64(len(content)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	runtime.ReadMemStats(&before)
+	for i := 0; i < b.N; i++ {
+		Parser{}.Parse(content)
+	}
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.Mallocs - before.Mallocs) / float64(b.N * keys), "allocs/key")
+	b.ReportMetric(float64(after.TotalAlloc - before.TotalAlloc) / float64(b.N * keys), "B/key")
+}
+
+func BenchmarkParseSmall(b *testing.B) {
+	content, err := ioutil.ReadFile("tests/test2.toml")
+	if err != nil { b.Fatal(err) }
+	benchmarkParse(b, string(content))
+}
+
+// About 2 MB of sections with keys of every kind
+func largeDocument() string {
+	var output strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&output, "# Server %d\n[servers.server%d]\n", i, i)
+		fmt.Fprintf(&output, "ip = \"10.0.%d.%d\" # Private\n", i / 256, i % 256)
+		fmt.Fprintf(&output, "port = %d\n", 8000 + i)
+		fmt.Fprintf(&output, "enabled = %t\n", i % 2 == 0)
+		fmt.Fprintf(&output, "weight = %d.5\n", i)
+		fmt.Fprintf(&output, "created = 1979-05-27T07:32:00Z\n")
+		fmt.Fprintf(&output, "description = \"Server number %d, with an escaped \\\"quote\\\" and a tab\\t\"\n", i)
+		fmt.Fprintf(&output, "tags = [ \"alpha\", \"beta\", \"gamma\" ]\n")
+		fmt.Fprintf(&output, "ports = [\n  %d,\n  %d, # Admin\n  %d,\n]\n\n", i, i + 1, i + 2)
+	}
+	return output.String()
+}
+
+func BenchmarkParseLarge(b *testing.B) {
+	benchmarkParse(b, largeDocument())
+}
+
+// Deeply nested sections and arrays
+func nestedDocument() string {
+	var output strings.Builder
+	path := "a"
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&output, "[%s]\n", path)
+		fmt.Fprintf(&output, "value = %s1%s\n", strings.Repeat("[", i + 1), strings.Repeat("]", i + 1))
+		fmt.Fprintf(&output, "matrix = [%s[]]\n\n", strings.Repeat("[1, 2], [3, 4], ", 10))
+		path += fmt.Sprintf(".s%d", i)
+	}
+	return output.String()
+}
+
+func BenchmarkParseNested(b *testing.B) {
+	benchmarkParse(b, nestedDocument())
+}
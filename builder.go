@@ -0,0 +1,214 @@
+package toml
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Documents are never modified once they have been parsed or built, so they
+// can be shared between goroutines. A Builder creates a new document, either
+// from scratch or from an existing one, which is copied on the first edit.
+type Builder struct {
+	root *Node
+	shared bool
+}
+
+func NewBuilder() *Builder {
+	return &Builder{root: newDocument().root}
+}
+
+func (this Document) Builder() *Builder {
+	return &Builder{root: this.root, shared: true}
+}
+
+func (this *Builder) edit() {
+	if !this.shared { return }
+	this.root = this.root.Clone()
+	this.shared = false
+}
+
+func (this *Builder) find(names []string) (*Node, bool) {
+	current := this.root
+	for _, name := range names {
+		node, ok := current.Child(name)
+		if !ok { return nil, false }
+		current = node
+	}
+	return current, true
+}
+
+// Checks that the sections of the given path either exist or can be created
+func (this *Builder) checkSections(names []string) error {
+	current := this.root
+	for i, name := range names {
+		if name == "" { return errors.New("toml: empty key in path " + strings.Join(names, ".")) }
+		if current == nil { continue }
+		node, ok := current.Child(name)
+		if !ok {
+			current = nil
+			continue
+		}
+		if node.kind != kindSection { return errors.New("toml: " + strings.Join(names[0:i + 1], ".") + " is not a section") }
+		current = node
+	}
+	return nil
+}
+
+// Creates the missing sections of the given path, and returns the last one
+func (this *Builder) makeSections(names []string) *Node {
+	current := this.root
+	for _, name := range names {
+		node, ok := current.Child(name)
+		if !ok {
+			node = newNodePointer()
+			node.name = name
+			node.kind = kindSection
+			current.setChild(name, node)
+		}
+		current = node
+	}
+	return current
+}
+
+// Sets the value at the given key path, creating the missing sections
+func (this *Builder) Set(path string, value Value) error {
+	return this.set(strings.Split(path, "."), value)
+}
+
+func (this *Builder) set(names []string, value Value) error {
+	path := strings.Join(names, ".")
+	if value.kind == 0 { return errors.New("toml: undefined value for " + path) }
+	if err := this.checkSections(names[0:len(names) - 1]); err != nil { return err }
+	if names[len(names) - 1] == "" { return errors.New("toml: empty key in path " + path) }
+	if node, ok := this.find(names); ok && node.kind != kindValue { return errors.New("toml: " + path + " is a section") }
+
+	this.edit()
+	section := this.makeSections(names[0:len(names) - 1])
+	name := names[len(names) - 1]
+	node, ok := section.Child(name)
+	if !ok {
+		node = newNodePointer()
+		node.name = name
+		node.kind = kindValue
+		section.setChild(name, node)
+	}
+	node.value = keepSensitive(node.value, value)
+	return nil
+}
+
+// Creates the section at the given key path, and its parents, if they don't
+// exist yet.
+func (this *Builder) AddSection(path string) error {
+	names := strings.Split(path, ".")
+	if err := this.checkSections(names); err != nil { return err }
+	this.edit()
+	this.makeSections(names)
+	return nil
+}
+
+// Removes the value or section at the given key path. Returns false if there
+// is nothing to remove.
+func (this *Builder) Delete(path string) bool {
+	names := strings.Split(path, ".")
+	if _, ok := this.find(names); !ok { return false }
+	this.edit()
+	node, _ := this.find(names)
+	node.parent.removeChild(node.name)
+	return true
+}
+
+// Returns the document built so far. Later edits don't change it.
+func (this *Builder) Document() Document {
+	this.shared = true
+	return Document{this.root}
+}
+
+func NewBool(v bool) Value {
+	var output Value
+	output.kind = kindBool
+	if v { output.scalar = 1 }
+	return output
+}
+
+func NewString(v string) Value {
+	var output Value
+	output.kind = kindString
+	output.text = v
+	return output
+}
+
+func NewInt(v int64) Value {
+	var output Value
+	output.kind = kindInt
+	output.scalar = uint64(v)
+	return output
+}
+
+// Values that fit in an int64 are stored as such
+func NewBigInt(v *big.Int) Value {
+	if v.IsInt64() { return NewInt(v.Int64()) }
+	var output Value
+	output.kind = kindBigInt
+	output.text = v.String()
+	return output
+}
+
+func NewFloat(v float64) Value {
+	var output Value
+	output.kind = kindFloat
+	output.scalar = math.Float64bits(v)
+	return output
+}
+
+// The location of the date is kept as a fixed zone
+func NewDate(v time.Time) Value {
+	var output Value
+	output.setDate(v)
+	return output
+}
+
+func NewLocalDate(v LocalDate) Value {
+	var output Value
+	output.kind = kindLocalDate
+	output.scalar = uint64(v.AsTime(time.UTC).Unix())
+	return output
+}
+
+// The precision of the time is kept, for when the value is written
+func NewLocalTime(v LocalTime) Value {
+	var output Value
+	output.kind = kindLocalTime
+	output.scalar = uint64(v.Hour * 3600 + v.Minute * 60 + v.Second)
+	output.nsec = int32(v.Nanosecond)
+	output.flags = uint8(clampPrecision(v.Precision))
+	return output
+}
+
+func NewLocalDateTime(v LocalDateTime) Value {
+	var output Value
+	output.kind = kindLocalDateTime
+	output.scalar = uint64(v.AsTime(time.UTC).Unix())
+	output.nsec = int32(v.Nanosecond)
+	output.flags = uint8(clampPrecision(v.Precision))
+	return output
+}
+
+func NewArray(values ...Value) Value {
+	var output Value
+	output.kind = kindArray
+	output.array = make([]Value, len(values))
+	copy(output.array, values)
+	return output
+}
+
+// Parses a TOML value, such as `"a string"`, `[1, 2]` or `1979-05-27T07:32:00Z`
+func ParseValue(s string) (Value, error) {
+	s = strings.Trim(s, " \t\n\r")
+	v, index, ok := parseValue(s)
+	if !ok || index != len(s) { return v, fmt.Errorf("toml: invalid value: %q", s) }
+	return v, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	toml "../.."
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Generates Go struct definitions with `toml` tags from sample TOML files, or
+// a JSON Schema with -schema. The shapes of all the samples are merged into
+// one struct. For example:
+//
+//	//go:generate toml2go -package config -type Config -o config_gen.go config.toml
+
+func main() {
+	packageName := flag.String("package", "main", "Package of the generated file")
+	typeName := flag.String("type", "Config", "Name of the generated struct type")
+	outputPath := flag.String("o", "", "Output file, instead of the standard output")
+	schema := flag.Bool("schema", false, "Generate a JSON Schema instead of a struct")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: toml2go [-package name] [-type name] [-o file] [-schema] <sample.toml>...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	
+	var samples []toml.Document
+	var names []string
+	for _, path := range flag.Args() {
+		doc, err := toml.Parser{}.TryParseFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		samples = append(samples, doc)
+		names = append(names, filepath.Base(path))
+	}
+	
+	var output string
+	if *schema {
+		source, err := toml.DocumentSchema(samples...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		output = string(source) + "\n"
+	} else {
+		source, err := toml.GenerateStruct(*packageName, *typeName, samples...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		output = "// Code generated by toml2go from " + strings.Join(names, ", ") + ". DO NOT EDIT.\n\n" + string(source)
+	}
+	
+	if *outputPath == "" {
+		fmt.Print(output)
+		return
+	}
+	if err := ioutil.WriteFile(*outputPath, []byte(output), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
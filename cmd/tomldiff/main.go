@@ -0,0 +1,54 @@
+package main
+
+import (
+	toml "../.."
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Prints the values that differ between two TOML files. Exits with status 1
+// if the files differ, and 2 if they can't be compared. The values matched by
+// the -sensitive patterns are redacted.
+
+func main() {
+	jsonOutput := flag.Bool("json", false, "Print the changes as JSON")
+	sensitive := flag.String("sensitive", "", "Comma-separated patterns of the values to redact, eg. **.password")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: tomldiff [-json] [-sensitive patterns] <old.toml> <new.toml>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	
+	var patterns []string
+	if *sensitive != "" { patterns = strings.Split(*sensitive, ",") }
+	var docs [2]toml.Document
+	for i := range docs {
+		doc, err := toml.Parser{}.TryParseFile(flag.Arg(i))
+		if err == nil { doc, err = doc.MarkSensitive(patterns...) }
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		docs[i] = doc
+	}
+	changes := toml.Diff(docs[0], docs[1])
+	
+	if *jsonOutput {
+		output, err := toml.FormatChangesJSON(changes)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		fmt.Print(output)
+	} else {
+		fmt.Print(toml.FormatChanges(changes))
+	}
+	
+	if len(changes) > 0 { os.Exit(1) }
+}
@@ -0,0 +1,65 @@
+package toml
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Returned by the checked conversions when the value doesn't fit in the type
+type RangeError struct {
+	Value string
+	Type string
+}
+
+func (this *RangeError) Error() string {
+	return fmt.Sprintf("toml: %s is out of range for %s", this.Value, this.Type)
+}
+
+func (this Value) checkedInt(bits uint, typeName string) (int64, error) {
+	if this.kind == kindBigInt { return 0, &RangeError{this.redact(this.text), typeName} }
+	if this.kind != kindInt { return 0, fmt.Errorf("toml: expected int value, got %s", this.kind) }
+	output := int64(this.scalar)
+	if bits < 64 && (output < -1 << (bits - 1) || output > 1 << (bits - 1) - 1) { return 0, &RangeError{this.redact(strconv.FormatInt(output, 10)), typeName} }
+	return output, nil
+}
+
+// Returns the integer, or an error if the value is not an integer or doesn't
+// fit in an int
+func (this Value) Int() (int, error) {
+	output, err := this.checkedInt(strconv.IntSize, "int")
+	return int(output), err
+}
+
+func (this Value) Int8() (int8, error) {
+	output, err := this.checkedInt(8, "int8")
+	return int8(output), err
+}
+
+func (this Value) Int16() (int16, error) {
+	output, err := this.checkedInt(16, "int16")
+	return int16(output), err
+}
+
+func (this Value) Int32() (int32, error) {
+	output, err := this.checkedInt(32, "int32")
+	return int32(output), err
+}
+
+func (this Value) Int64() (int64, error) {
+	return this.checkedInt(64, "int64")
+}
+
+// Returns the float, or an error if the value is not a float or is too large
+// for a float32
+func (this Value) Float32() (float32, error) {
+	output, err := this.Float64()
+	if err != nil { return 0, err }
+	if math.Abs(output) > math.MaxFloat32 && !math.IsInf(output, 0) { return 0, &RangeError{this.String(), "float32"} }
+	return float32(output), nil
+}
+
+func (this Value) Float64() (float64, error) {
+	if this.kind != kindFloat { return 0, fmt.Errorf("toml: expected float value, got %s", this.kind) }
+	return this.AsFloat64(), nil
+}
@@ -0,0 +1,198 @@
+package toml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A date without a time or an offset, eg. 1979-05-27
+type LocalDate struct {
+	Year int
+	Month time.Month
+	Day int
+}
+
+func (this LocalDate) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", this.Year, int(this.Month), this.Day)
+}
+
+// Returns the start of the day in the given location
+func (this LocalDate) AsTime(location *time.Location) time.Time {
+	return time.Date(this.Year, this.Month, this.Day, 0, 0, 0, 0, location)
+}
+
+// A time of day without a date or an offset, eg. 07:32:00.999
+type LocalTime struct {
+	Hour int
+	Minute int
+	Second int
+	Nanosecond int
+	Precision int // Number of digits of the fractional seconds, or 0 to write as few as needed
+}
+
+func (this LocalTime) String() string {
+	return fmt.Sprintf("%02d:%02d:%02d", this.Hour, this.Minute, this.Second) + formatFraction(this.Nanosecond, this.Precision)
+}
+
+// A date and a time without an offset, eg. 1979-05-27T07:32:00
+type LocalDateTime struct {
+	LocalDate
+	LocalTime
+}
+
+func (this LocalDateTime) String() string {
+	return this.LocalDate.String() + "T" + this.LocalTime.String()
+}
+
+// Returns the date and time in the given location
+func (this LocalDateTime) AsTime(location *time.Location) time.Time {
+	return time.Date(this.Year, this.Month, this.Day, this.Hour, this.Minute, this.Second, this.Nanosecond, location)
+}
+
+func isDateKind(kind Kind) bool {
+	return kind == kindDate || kind == kindLocalDate || kind == kindLocalTime || kind == kindLocalDateTime
+}
+
+func (this Value) AsLocalDate() LocalDate {
+	if this.kind != kindLocalDate { return LocalDate{} }
+	return this.AsLocalDateTime().LocalDate
+}
+
+func (this Value) AsLocalTime() LocalTime {
+	if this.kind != kindLocalTime { return LocalTime{} }
+	seconds := int(this.scalar)
+	return LocalTime{seconds / 3600, seconds / 60 % 60, seconds % 60, int(this.nsec), this.precision()}
+}
+
+func (this Value) AsLocalDateTime() LocalDateTime {
+	if this.kind != kindLocalDate && this.kind != kindLocalDateTime { return LocalDateTime{} }
+	t := time.Unix(int64(this.scalar), int64(this.nsec)).UTC()
+	return LocalDateTime{
+		LocalDate{t.Year(), t.Month(), t.Day()},
+		LocalTime{t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), this.precision()},
+	}
+}
+
+// Writes the date kinds as TOML, with the offset and the number of digits of
+// the fractional seconds they were parsed with
+func (this Value) formatDate() string {
+	switch this.kind {
+	case kindLocalDate: return this.AsLocalDate().String()
+	case kindLocalTime: return this.AsLocalTime().String()
+	case kindLocalDateTime: return this.AsLocalDateTime().String()
+	}
+
+	t := this.AsDate()
+	output := t.Format("2006-01-02T15:04:05") + formatFraction(t.Nanosecond(), this.precision())
+	if this.zone == 0 && this.text == "" { return output + "Z" }
+	zone := int(this.zone)
+	sign := "+"
+	if zone < 0 {
+		sign = "-"
+		zone = -zone
+	}
+	return output + fmt.Sprintf("%s%02d:%02d", sign, zone / 60, zone % 60)
+}
+
+func formatFraction(nsec int, precision int) string {
+	digits := fmt.Sprintf("%09d", nsec)
+	if precision <= 0 {
+		digits = strings.TrimRight(digits, "0")
+	} else if precision < 9 {
+		digits = digits[0:precision]
+	}
+	if digits == "" { return "" }
+	return "." + digits
+}
+
+// Returns true if s starts like a date (1979-) or a time (07:)
+func isDateTime(s string) bool {
+	if len(s) < 3 || !isDigit(s[0]) || !isDigit(s[1]) { return false }
+	return s[2] == ':' || (len(s) >= 5 && isDigit(s[2]) && isDigit(s[3]) && s[4] == '-')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Returns the number written with the given count of digits at the start of
+// s, or -1
+func parseDigits(s string, count int) int {
+	if len(s) < count { return -1 }
+	output := 0
+	for i := 0; i < count; i++ {
+		if !isDigit(s[i]) { return -1 }
+		output = output * 10 + int(s[i] - '0')
+	}
+	return output
+}
+
+// Parses an offset date-time, a local date-time, a local date or a local time
+// at the start of s. The date and time can be separated by T or a space, and
+// digits of the fractional seconds beyond nanoseconds are truncated.
+func parseDateTime(s string) (Value, int, bool) {
+	var v Value
+	var date LocalDate
+	index := 0
+	hasDate := len(s) >= 5 && s[4] == '-'
+	if hasDate {
+		if len(s) < 10 || s[7] != '-' { return v, 0, false }
+		date = LocalDate{parseDigits(s, 4), time.Month(parseDigits(s[5:len(s)], 2)), parseDigits(s[8:len(s)], 2)}
+		if date.Year < 0 || date.Month < 1 || date.Month > 12 || date.Day < 1 { return v, 0, false }
+		if date.Day > time.Date(date.Year, date.Month + 1, 0, 0, 0, 0, 0, time.UTC).Day() { return v, 0, false }
+		index = 10
+		if index + 1 < len(s) && (s[index] == 'T' || s[index] == 't' || (s[index] == ' ' && isDigit(s[index + 1]))) {
+			index++
+		} else {
+			v.kind = kindLocalDate
+			v.scalar = uint64(date.AsTime(time.UTC).Unix())
+			return v, index, true
+		}
+	}
+
+	t := s[index:len(s)]
+	if len(t) < 8 || t[2] != ':' || t[5] != ':' { return v, 0, false }
+	clock := LocalTime{Hour: parseDigits(t, 2), Minute: parseDigits(t[3:len(t)], 2), Second: parseDigits(t[6:len(t)], 2)}
+	if clock.Hour < 0 || clock.Hour > 23 || clock.Minute < 0 || clock.Minute > 59 || clock.Second < 0 || clock.Second > 59 { return v, 0, false }
+	index += 8
+	if index < len(s) && s[index] == '.' {
+		index++
+		start := index
+		for index < len(s) && isDigit(s[index]) { index++ }
+		if index == start { return v, 0, false }
+		fraction := s[start:index]
+		if len(fraction) > 9 { fraction = fraction[0:9] }
+		clock.Precision = len(fraction)
+		clock.Nanosecond, _ = strconv.Atoi(fraction + strings.Repeat("0", 9 - len(fraction)))
+	}
+	v.nsec = int32(clock.Nanosecond)
+	v.flags = uint8(clock.Precision)
+
+	if !hasDate {
+		v.kind = kindLocalTime
+		v.scalar = uint64(clock.Hour * 3600 + clock.Minute * 60 + clock.Second)
+		return v, index, true
+	}
+
+	dateTime := LocalDateTime{date, clock}
+	if index < len(s) && (s[index] == 'Z' || s[index] == 'z') {
+		v.setDate(dateTime.AsTime(time.UTC))
+		return v, index + 1, true
+	}
+	if index < len(s) && (s[index] == '+' || s[index] == '-') {
+		hours := parseDigits(s[index + 1:len(s)], 2)
+		minutes := -1
+		if len(s) >= index + 6 && s[index + 3] == ':' { minutes = parseDigits(s[index + 4:len(s)], 2) }
+		if hours < 0 || hours > 23 || minutes < 0 || minutes > 59 { return v, 0, false }
+		zone := hours * 60 + minutes
+		if s[index] == '-' { zone = -zone }
+		v.setDate(dateTime.AsTime(time.FixedZone("", zone * 60)))
+		return v, index + 6, true
+	}
+
+	v.kind = kindLocalDateTime
+	v.scalar = uint64(dateTime.AsTime(time.UTC).Unix())
+	return v, index, true
+}
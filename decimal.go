@@ -0,0 +1,72 @@
+package toml
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Formats a float as a valid TOML float literal: 1.0, 0.25, 1e-09 is written
+// 1e-9, and infinities and NaN are written inf, -inf and nan.
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) { return "inf" }
+	if math.IsInf(f, -1) { return "-inf" }
+	if math.IsNaN(f) { return "nan" }
+
+	abs := math.Abs(f)
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		s := strconv.FormatFloat(f, 'e', -1, 64)
+		index := strings.IndexByte(s, 'e')
+		exponent, _ := strconv.Atoi(s[index + 1:len(s)])
+		return s[0:index] + "e" + strconv.Itoa(exponent)
+	}
+
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if strings.IndexByte(s, '.') < 0 { s += ".0" }
+	return s
+}
+
+// Returns the number as a plain decimal, without exponent, and with every
+// digit it was written with, eg. "0.000000001" for 1e-9 and "1.50" for 1.50.
+// Floats that were not parsed are written with the fewest digits that
+// represent them exactly. Returns an empty string for infinities, NaN and
+// non-numeric values.
+func (this Value) AsDecimalString() string {
+	switch this.kind {
+	case kindInt: return strconv.FormatInt(this.AsInt64(), 10)
+	case kindBigInt: return this.text
+	case kindFloat:
+		f := this.AsFloat64()
+		if math.IsInf(f, 0) || math.IsNaN(f) { return "" }
+		if this.text != "" {
+			if s, ok := expandExponent(this.text); ok { return s }
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return ""
+}
+
+// Rewrites a float literal such as 1.5e-3 without its exponent. Returns false
+// for very large exponents, which would make a huge string.
+func expandExponent(s string) (string, bool) {
+	mantissa := s
+	exponent := 0
+	if index := strings.IndexAny(s, "eE"); index >= 0 {
+		var err error
+		mantissa = s[0:index]
+		exponent, err = strconv.Atoi(s[index + 1:len(s)])
+		if err != nil || exponent > 10000 || exponent < -10000 { return "", false }
+	}
+
+	decimals := 0
+	if index := strings.IndexByte(mantissa, '.'); index >= 0 { decimals = len(mantissa) - index - 1 }
+	decimals -= exponent
+	if decimals < 0 { decimals = 0 }
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok { return "", false }
+	output := r.FloatString(decimals)
+	if r.Sign() == 0 && s[0] == '-' { output = "-" + output }
+	return output, true
+}
@@ -0,0 +1,569 @@
+package toml
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Returned by Decode when a value can't be stored in the field of its key
+type DecodeError struct {
+	Path string // Full name of the key, as returned by Node.FullName(), with the index of array elements
+	Position Position
+	Err error
+}
+
+func (this *DecodeError) Error() string {
+	message := this.Path + ": " + strings.TrimPrefix(this.Err.Error(), "toml: ")
+	if this.Position.IsValid() { return this.Position.String() + ": " + message }
+	return "toml: " + message
+}
+
+func (this *DecodeError) Unwrap() error {
+	return this.Err
+}
+
+// Implemented by the types that decode themselves from a TOML value. The
+// errors are returned by Decode() as a DecodeError with the key path.
+type Unmarshaler interface {
+	UnmarshalTOML(value Value) error
+}
+
+var (
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	byteSizeType = reflect.TypeOf(ByteSize(0))
+	localDateType = reflect.TypeOf(LocalDate{})
+	localTimeType = reflect.TypeOf(LocalTime{})
+	localDateTimeType = reflect.TypeOf(LocalDateTime{})
+)
+
+// Options of Decode(). The zero value is the default configuration.
+type DecoderConfig struct {
+	DurationUnit time.Duration // Unit of the integers decoded into time.Duration fields, nanoseconds if zero
+	Strict bool // Fail on the keys and sections that match no field, see MetaData.Undecoded()
+	Hooks []DecodeHook // Run in order on every value and section before it's decoded
+	Defaults Document // Merged beneath the decoded document, see Document.WithDefaults()
+}
+
+// Decodes the document into the struct or map that v points to. Keys are
+// matched to the fields by their `toml` tag, or else by their name, ignoring
+// case, and the fields of embedded structs are promoted. Fields without a key
+// are left unchanged, maps are merged and pointers are only allocated for the
+// keys and sections that exist. Values decoded into interface{} are bool,
+// string, int64, *big.Int, float64, time.Time, LocalDate, LocalTime,
+// LocalDateTime, []interface{} or map[string]interface{}. The values of the
+// fields with the sensitive option, eg. `toml:"password,sensitive"`, are
+// redacted in the error messages. Local dates and date-times can be decoded
+// into time.Time fields, in the local time zone, and strings such as "1m30s"
+// and "10MiB" into time.Duration and ByteSize fields. Types that implement
+// Unmarshaler decode themselves, and strings are decoded into the types that
+// implement encoding.TextUnmarshaler, such as net.IP. The fields of missing
+// keys are set to the value of their `default` tag, if any, unless they are
+// already set.
+func (this Document) Decode(v interface{}) error {
+	_, err := DecoderConfig{}.DecodeSection(this.root, v)
+	return err
+}
+
+// Decodes the section into the struct that v points to, see Document.Decode()
+func (this *Node) Decode(v interface{}) error {
+	_, err := DecoderConfig{}.DecodeSection(this, v)
+	return err
+}
+
+// Decodes the document with these options, see Document.Decode(), and returns
+// which keys were decoded. The metadata and the Strict check only cover the
+// keys of the document, not those that only are in Defaults.
+func (this DecoderConfig) Decode(doc Document, v interface{}) (MetaData, error) {
+	merged := doc.WithDefaults(this.Defaults)
+	if merged.root == doc.root { return this.DecodeSection(doc.root, v) }
+	config := this
+	config.Strict = false
+	metaData, err := config.DecodeSection(merged.root, v)
+	if doc.root == nil { return MetaData{}, err }
+	decoded := make(map[*Node]bool)
+	mapDecoded(doc.root, merged.root, metaData.decoded, decoded)
+	metaData = MetaData{doc.root, decoded, metaData.keys, metaData.structs}
+	if err == nil && this.Strict { err = metaData.strictError() }
+	return metaData, err
+}
+
+// Marks the nodes of the document whose copy in the merged document was
+// decoded
+func mapDecoded(node *Node, merged *Node, mergedDecoded map[*Node]bool, decoded map[*Node]bool) {
+	if mergedDecoded[merged] { decoded[node] = true }
+	for _, child := range node.order {
+		if mergedChild, ok := merged.Child(child.name); ok { mapDecoded(child, mergedChild, mergedDecoded, decoded) }
+	}
+}
+
+// Decodes the section with these options, see Document.Decode(). The Defaults
+// document is not used.
+func (this DecoderConfig) DecodeSection(node *Node, v interface{}) (MetaData, error) {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() { return MetaData{}, errors.New("toml: Decode needs a non-nil pointer") }
+	decoder := newDecoder(this)
+	err := decoder.node(node, target.Elem())
+	metaData := MetaData{node, decoder.decoded, decoder.keys, decoder.structs}
+	if err == nil && this.Strict { err = metaData.strictError() }
+	return metaData, err
+}
+
+// Decodes a single value, such as a table given to a decode hook, into what v
+// points to
+func (this DecoderConfig) DecodeValue(value Value, v interface{}) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() { return errors.New("toml: Decode needs a non-nil pointer") }
+	return newDecoder(this).value(value, target.Elem(), "")
+}
+
+// Decodes the value into what v points to, see Document.Decode()
+func (this Value) Decode(v interface{}) error {
+	return DecoderConfig{}.DecodeValue(this, v)
+}
+
+type decoder struct {
+	config DecoderConfig
+	decoded map[*Node]bool
+	keys map[string]bool // Paths of the keys of the tables in values that were decoded into struct fields
+	structs map[string]bool // Paths of the tables in values that were decoded into structs
+	sensitive int // Depth of the fields with the sensitive option being decoded
+}
+
+func newDecoder(config DecoderConfig) *decoder {
+	return &decoder{config: config, decoded: make(map[*Node]bool), keys: make(map[string]bool), structs: make(map[string]bool)}
+}
+
+func (this *decoder) markDecoded(node *Node) {
+	this.decoded[node] = true
+	for _, child := range node.Children() {
+		this.markDecoded(child)
+	}
+}
+
+func (this *decoder) node(node *Node, target reflect.Value) error {
+	this.decoded[node] = true
+	if node.kind == kindValue { return this.value(node.value, target, node.FullName()) }
+
+	if len(this.config.Hooks) > 0 {
+		table := sectionValue(node)
+		if this.sensitive > 0 { table = Sensitive(table) }
+		done, replaced, err := this.hook(&table, target, node.FullName(), node.Position())
+		if done || replaced || err != nil { this.markDecoded(node) }
+		if err != nil || done { return err }
+		if replaced { return this.value(table, target, node.FullName()) }
+	}
+
+	if target.CanAddr() && reflect.PtrTo(target.Type()).Implements(unmarshalerType) {
+		this.markDecoded(node)
+		table := sectionValue(node)
+		if this.sensitive > 0 { table = Sensitive(table) }
+		if err := target.Addr().Interface().(Unmarshaler).UnmarshalTOML(table); err != nil { return unmarshalError(node.FullName(), node.Position(), table, err) }
+		return nil
+	}
+
+	switch {
+	case target.Kind() == reflect.Ptr:
+		if target.IsNil() { target.Set(reflect.New(target.Type().Elem())) }
+		return this.node(node, target.Elem())
+
+	case target.Kind() == reflect.Interface && target.NumMethod() == 0:
+		output, ok := target.Interface().(map[string]interface{})
+		if !ok || output == nil { output = make(map[string]interface{}) }
+		if err := this.mapNode(node, reflect.ValueOf(output)); err != nil { return err }
+		target.Set(reflect.ValueOf(output))
+
+	case target.Kind() == reflect.Map:
+		if target.IsNil() { target.Set(reflect.MakeMap(target.Type())) }
+		return this.mapNode(node, target)
+
+	case target.Kind() == reflect.Struct && !isDateType(target.Type()):
+		for _, field := range structFields(target.Type()) {
+			child, ok := findChild(node, field.key)
+			if !ok {
+				if fieldValue, ok := fieldByIndex(target, field.index, false); ok {
+					if err := this.setDefault(field.field, fieldValue, joinPath(node.FullName(), field.key)); err != nil { return err }
+				}
+				continue
+			}
+			if fieldValue, ok := fieldByIndex(target, field.index, true); ok {
+				if isSensitiveField(field.field) { this.sensitive++ }
+				err := this.node(child, fieldValue)
+				if isSensitiveField(field.field) { this.sensitive-- }
+				if err != nil { return err }
+			}
+		}
+
+	default:
+		return decodeError(node.FullName(), node.Position(), "cannot decode section into %s", target.Type())
+	}
+	return nil
+}
+
+// Decodes the keys and sections into the map, keeping the entries that are
+// already in it
+func (this *decoder) mapNode(node *Node, target reflect.Value) error {
+	for _, child := range node.Children() {
+		key, err := mapKey(child.name, target.Type().Key())
+		if err != nil { return &DecodeError{child.FullName(), child.Position(), err} }
+		element := reflect.New(target.Type().Elem()).Elem()
+		if existing := target.MapIndex(key); existing.IsValid() { element.Set(existing) }
+		if err := this.node(child, element); err != nil { return err }
+		target.SetMapIndex(key, element)
+	}
+	return nil
+}
+
+// Converts a key to the key type of a map, which must be a string or implement
+// encoding.TextUnmarshaler
+func mapKey(name string, keyType reflect.Type) (reflect.Value, error) {
+	key := reflect.New(keyType).Elem()
+	if reflect.PtrTo(keyType).Implements(textUnmarshalerType) {
+		err := key.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(name))
+		return key, err
+	}
+	if keyType.Kind() != reflect.String { return key, fmt.Errorf("cannot decode key into %s", keyType) }
+	key.SetString(name)
+	return key, nil
+}
+
+// A field of a struct, or of a struct embedded in it
+type structField struct {
+	key string
+	index []int
+	field reflect.StructField
+}
+
+// Returns the decoded fields of a struct type. The fields of the embedded
+// structs without a `toml` tag are promoted, unless a field closer to the
+// surface has the same key.
+func structFields(structType reflect.Type) []structField {
+	var output []structField
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		embedded := field.Type
+		if embedded.Kind() == reflect.Ptr { embedded = embedded.Elem() }
+		if field.Anonymous && field.Tag.Get("toml") == "" && embedded.Kind() == reflect.Struct && !isDateType(embedded) {
+			if field.PkgPath != "" && field.Type.Kind() == reflect.Ptr { continue }
+			for _, promoted := range structFields(embedded) {
+				promoted.index = append([]int{i}, promoted.index...)
+				output = append(output, promoted)
+			}
+			continue
+		}
+		key := fieldKey(field)
+		if key == "" { continue }
+		output = append(output, structField{key, []int{i}, field})
+	}
+
+	depths := make(map[string]int)
+	for _, field := range output {
+		if depth, ok := depths[field.key]; !ok || len(field.index) < depth { depths[field.key] = len(field.index) }
+	}
+	visible := output[0:0]
+	for _, field := range output {
+		if len(field.index) != depths[field.key] { continue }
+		visible = append(visible, field)
+		depths[field.key] = -1 // The first of the fields with the same key and depth wins
+	}
+	return visible
+}
+
+// Returns the field of a struct or of one of its embedded structs. Nil
+// embedded pointers are allocated, or else the field is not found.
+func fieldByIndex(target reflect.Value, index []int, allocate bool) (reflect.Value, bool) {
+	for i, fieldIndex := range index {
+		if i > 0 && target.Kind() == reflect.Ptr {
+			if target.IsNil() {
+				if !allocate || !target.CanSet() { return reflect.Value{}, false }
+				target.Set(reflect.New(target.Type().Elem()))
+			}
+			target = target.Elem()
+		}
+		target = target.Field(fieldIndex)
+	}
+	return target, true
+}
+
+// Returns the value as a bool, string, int64, *big.Int, float64, time.Time,
+// LocalDate, LocalTime, LocalDateTime, []interface{} or map[string]interface{}
+func (this Value) interfaceValue() interface{} {
+	switch this.kind {
+	case kindBool: return this.AsBool()
+	case kindString: return this.AsString()
+	case kindInt: return this.AsInt64()
+	case kindBigInt: return this.AsBigInt()
+	case kindFloat: return this.AsFloat64()
+	case kindDate: return this.AsDate()
+	case kindLocalDate: return this.AsLocalDate()
+	case kindLocalTime: return this.AsLocalTime()
+	case kindLocalDateTime: return this.AsLocalDateTime()
+	case kindArray:
+		output := make([]interface{}, len(this.array))
+		for i, element := range this.array {
+			output[i] = element.interfaceValue()
+		}
+		return output
+	case kindTable:
+		output := make(map[string]interface{})
+		for i := 0; i + 1 < len(this.array); i += 2 {
+			output[this.array[i].text] = this.array[i + 1].interfaceValue()
+		}
+		return output
+	}
+	return nil
+}
+
+// Returns the key of a struct field: the name in its `toml` tag, or else the
+// name of the field. Returns an empty string for fields that are not decoded.
+func fieldKey(field reflect.StructField) string {
+	if field.PkgPath != "" { return "" }
+	name := strings.Split(field.Tag.Get("toml"), ",")[0]
+	if name == "-" { return "" }
+	if name == "" { return field.Name }
+	return name
+}
+
+// Returns the child with the given name, or else the first one whose name
+// only differs by case
+func findChild(node *Node, name string) (*Node, bool) {
+	if child, ok := node.Child(name); ok { return child, true }
+	for _, child := range node.Children() {
+		if strings.EqualFold(child.name, name) { return child, true }
+	}
+	return nil, false
+}
+
+func isDateType(t reflect.Type) bool {
+	return t == timeType || t == localDateType || t == localTimeType || t == localDateTimeType
+}
+
+func decodeError(path string, position Position, format string, args ...interface{}) error {
+	return &DecodeError{path, position, fmt.Errorf(format, args...)}
+}
+
+// Returns the error of an Unmarshaler, whose text may contain the value, so
+// it's replaced if the value is sensitive
+func unmarshalError(path string, position Position, value Value, err error) error {
+	if value.IsSensitive() { return decodeError(path, position, "invalid %s value %s", value.kind, value) }
+	return &DecodeError{path, position, err}
+}
+
+// Returns the key of a table with the given name, or else the first one whose
+// name only differs by case
+func findKey(table Value, name string) (string, Value, bool) {
+	if value, ok := table.Get(name); ok { return name, value, true }
+	for _, key := range table.Keys() {
+		if strings.EqualFold(key, name) {
+			value, _ := table.Get(key)
+			return key, value, true
+		}
+	}
+	return "", Value{}, false
+}
+
+// Runs the hooks on the value, and reports their errors at the given position.
+// Returns done if a hook decoded it, or replaced if a hook converted it to
+// another value, which is then decoded instead.
+func (this *decoder) hook(value *Value, target reflect.Value, path string, position Position) (bool, bool, error) {
+	replaced := false
+	for _, hook := range this.config.Hooks {
+		output, err := hook(value.kind, target.Type(), *value)
+		if err != nil { return false, replaced, &DecodeError{path, position, err} }
+		if output == nil { continue }
+
+		if converted, ok := output.(Value); ok {
+			if converted.source == nil { converted.source, converted.offset, converted.end = value.source, value.offset, value.end }
+			*value = converted
+			replaced = true
+			continue
+		}
+		result := reflect.ValueOf(output)
+		if result.Type().AssignableTo(target.Type()) {
+			target.Set(result)
+		} else if result.Kind() == target.Kind() && result.Type().ConvertibleTo(target.Type()) {
+			target.Set(result.Convert(target.Type()))
+		} else {
+			return false, replaced, decodeError(path, position, "decode hook returned %s for %s", result.Type(), target.Type())
+		}
+		return true, replaced, nil
+	}
+	return false, replaced, nil
+}
+
+func (this *decoder) value(value Value, target reflect.Value, path string) error {
+	// Parse() keeps the invalid values of a document as undefined values
+	if value.kind == 0 { return decodeError(path, value.Position(), "invalid value") }
+	if this.sensitive > 0 && value.flags & flagSensitive == 0 { value = Sensitive(value) }
+	targetType := target.Type()
+	mismatch := func() error { return decodeError(path, value.Position(), "cannot decode %s into %s", value.kind, targetType) }
+
+	if len(this.config.Hooks) > 0 {
+		done, _, err := this.hook(&value, target, path, value.Position())
+		if err != nil || done { return err }
+	}
+
+	if targetType.Kind() == reflect.Ptr {
+		if target.IsNil() { target.Set(reflect.New(targetType.Elem())) }
+		return this.value(value, target.Elem(), path)
+	}
+
+	if target.CanAddr() && reflect.PtrTo(targetType).Implements(unmarshalerType) {
+		if err := target.Addr().Interface().(Unmarshaler).UnmarshalTOML(value); err != nil { return unmarshalError(path, value.Position(), value, err) }
+		return nil
+	}
+
+	switch targetType {
+	case timeType:
+		switch value.kind {
+		case kindDate: target.Set(reflect.ValueOf(value.AsDate()))
+		case kindLocalDate: target.Set(reflect.ValueOf(value.AsLocalDate().AsTime(time.Local)))
+		case kindLocalDateTime: target.Set(reflect.ValueOf(value.AsLocalDateTime().AsTime(time.Local)))
+		default: return mismatch()
+		}
+		return nil
+	case localDateType:
+		if value.kind != kindLocalDate { return mismatch() }
+		target.Set(reflect.ValueOf(value.AsLocalDate()))
+		return nil
+	case localTimeType:
+		if value.kind != kindLocalTime { return mismatch() }
+		target.Set(reflect.ValueOf(value.AsLocalTime()))
+		return nil
+	case localDateTimeType:
+		if value.kind != kindLocalDateTime { return mismatch() }
+		target.Set(reflect.ValueOf(value.AsLocalDateTime()))
+		return nil
+	case durationType:
+		if value.kind == kindString {
+			duration, err := value.Duration()
+			if err != nil { return &DecodeError{path, value.Position(), err} }
+			target.SetInt(int64(duration))
+			return nil
+		}
+		if value.kind != kindInt { return mismatch() }
+		unit := this.config.DurationUnit
+		if unit <= 0 { unit = time.Nanosecond }
+		n := value.AsInt64()
+		if n > math.MaxInt64 / int64(unit) || n < math.MinInt64 / int64(unit) { return &DecodeError{path, value.Position(), &RangeError{value.String(), "time.Duration"}} }
+		target.SetInt(n * int64(unit))
+		return nil
+	case byteSizeType:
+		if value.kind != kindString && value.kind != kindInt { return mismatch() }
+		size, err := value.ByteSize()
+		if err != nil { return &DecodeError{path, value.Position(), err} }
+		target.SetInt(int64(size))
+		return nil
+	}
+
+	if value.kind == kindString && target.CanAddr() && reflect.PtrTo(targetType).Implements(textUnmarshalerType) {
+		if err := target.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value.text)); err != nil { return unmarshalError(path, value.Position(), value, err) }
+		return nil
+	}
+
+	switch targetType.Kind() {
+	case reflect.Bool:
+		if value.kind != kindBool { return mismatch() }
+		target.SetBool(value.AsBool())
+
+	case reflect.String:
+		if value.kind != kindString { return mismatch() }
+		target.SetString(value.AsString())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.kind != kindInt && value.kind != kindBigInt { return mismatch() }
+		n, err := value.checkedInt(uint(targetType.Bits()), targetType.String())
+		if err != nil { return &DecodeError{path, value.Position(), err} }
+		target.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value.kind != kindInt && value.kind != kindBigInt { return mismatch() }
+		n := uint64(value.AsInt64())
+		fits := value.kind == kindInt && value.AsInt64() >= 0
+		if value.kind == kindBigInt {
+			big := value.AsBigInt()
+			fits = big != nil && big.IsUint64()
+			if fits { n = big.Uint64() }
+		}
+		if !fits || target.OverflowUint(n) { return &DecodeError{path, value.Position(), &RangeError{value.String(), targetType.String()}} }
+		target.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		f := value.AsFloat64()
+		if value.kind == kindInt {
+			f = float64(value.AsInt64())
+		} else if value.kind != kindFloat {
+			return mismatch()
+		}
+		if !math.IsInf(f, 0) && target.OverflowFloat(f) { return &DecodeError{path, value.Position(), &RangeError{value.String(), targetType.String()}} }
+		target.SetFloat(f)
+
+	case reflect.Slice:
+		if value.kind != kindArray { return mismatch() }
+		output := reflect.MakeSlice(targetType, len(value.array), len(value.array))
+		for i, element := range value.array {
+			if err := this.value(element, output.Index(i), path + "[" + strconv.Itoa(i) + "]"); err != nil { return err }
+		}
+		target.Set(output)
+
+	case reflect.Array:
+		if value.kind != kindArray { return mismatch() }
+		if len(value.array) > target.Len() { return decodeError(path, value.Position(), "%d elements don't fit in %s", len(value.array), targetType) }
+		for i := 0; i < target.Len(); i++ {
+			if i >= len(value.array) {
+				target.Index(i).Set(reflect.Zero(targetType.Elem()))
+			} else if err := this.value(value.array[i], target.Index(i), path + "[" + strconv.Itoa(i) + "]"); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Interface:
+		if targetType.NumMethod() != 0 { return mismatch() }
+		target.Set(reflect.ValueOf(value.interfaceValue()))
+
+	case reflect.Map:
+		if value.kind != kindTable { return mismatch() }
+		if target.IsNil() { target.Set(reflect.MakeMap(targetType)) }
+		for i := 0; i + 1 < len(value.array); i += 2 {
+			name := value.array[i].text
+			key, err := mapKey(name, targetType.Key())
+			if err != nil { return &DecodeError{joinPath(path, name), value.array[i + 1].Position(), err} }
+			element := reflect.New(targetType.Elem()).Elem()
+			if existing := target.MapIndex(key); existing.IsValid() { element.Set(existing) }
+			if err := this.value(value.array[i + 1], element, joinPath(path, name)); err != nil { return err }
+			target.SetMapIndex(key, element)
+		}
+
+	case reflect.Struct:
+		if value.kind != kindTable { return mismatch() }
+		this.structs[path] = true
+		for _, field := range structFields(targetType) {
+			key, element, ok := findKey(value, field.key)
+			if !ok {
+				if fieldValue, ok := fieldByIndex(target, field.index, false); ok {
+					if err := this.setDefault(field.field, fieldValue, joinPath(path, field.key)); err != nil { return err }
+				}
+				continue
+			}
+			if fieldValue, ok := fieldByIndex(target, field.index, true); ok {
+				this.keys[joinPath(path, key)] = true
+				if isSensitiveField(field.field) { this.sensitive++ }
+				err := this.value(element, fieldValue, joinPath(path, key))
+				if isSensitiveField(field.field) { this.sensitive-- }
+				if err != nil { return err }
+			}
+		}
+
+	default:
+		return mismatch()
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package toml
+
+import (
+	"reflect"
+)
+
+// Returns a copy of the document where the keys and sections that are only in
+// the defaults document are added. Sections that are in both are merged, and
+// the values of the document win, including arrays.
+func (this Document) WithDefaults(defaults Document) Document {
+	if defaults.root == nil { return this }
+	if this.root == nil { return Document{defaults.root.Clone()} }
+	output := this.root.Clone()
+	mergeDefaults(output, defaults.root)
+	return Document{output}
+}
+
+func mergeDefaults(node *Node, defaults *Node) {
+	for _, child := range defaults.Children() {
+		existing, ok := node.Child(child.name)
+		if !ok {
+			node.setChild(child.name, child.Clone())
+		} else if existing.kind == kindSection && child.kind == kindSection {
+			mergeDefaults(existing, child)
+		}
+	}
+}
+
+func joinPath(path string, key string) string {
+	if path == "" { return key }
+	return path + "." + key
+}
+
+// Sets the field to the value of its `default` tag, which is parsed like a
+// TOML value, except for strings which don't need to be quoted. Fields that
+// are already set, in a pre-populated target, are left as they are. The fields
+// of a struct without a default are set to their own defaults.
+func (this *decoder) setDefault(field reflect.StructField, target reflect.Value, path string) error {
+	text, ok := field.Tag.Lookup("default")
+	if ok && !target.IsZero() { return nil }
+	if !ok {
+		if target.Kind() != reflect.Struct || isDateType(target.Type()) { return nil }
+		for _, field := range structFields(target.Type()) {
+			fieldValue, ok := fieldByIndex(target, field.index, false)
+			if !ok { continue }
+			if err := this.setDefault(field.field, fieldValue, joinPath(path, field.key)); err != nil { return err }
+		}
+		return nil
+	}
+
+	kind := Kind(0)
+	if target.Kind() == reflect.String { kind = kindString }
+	value, err := parseValueAs(text, kind)
+	if err != nil { return decodeError(path, Position{}, "invalid default: %v", err) }
+	return this.value(value, target, path)
+}
@@ -0,0 +1,135 @@
+package toml
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+)
+
+type ChangeType int
+
+const (
+	Added ChangeType = 1
+	Removed ChangeType = 2
+	Modified ChangeType = 3
+)
+
+func (this ChangeType) String() string {
+	switch this {
+	case Added: return "added"
+	case Removed: return "removed"
+	case Modified: return "modified"
+	}
+	return "undefined"
+}
+
+type Change struct {
+	Path string
+	Type ChangeType
+	Old Value // Not set if the value was added
+	New Value // Not set if the value was removed
+}
+
+func (this Change) KindChanged() bool {
+	return this.Type == Modified && this.Old.kind != this.New.kind
+}
+
+func (this Change) String() string {
+	switch this.Type {
+	case Added: return "+ " + this.Path + " = " + this.New.String()
+	case Removed: return "- " + this.Path + " = " + this.Old.String()
+	}
+	output := "~ " + this.Path + " = " + this.Old.String() + " -> " + this.New.String()
+	if this.KindChanged() { output += " (" + this.Old.kind.String() + " -> " + this.New.kind.String() + ")" }
+	return output
+}
+
+func (this Change) MarshalJSON() ([]byte, error) {
+	output := map[string]interface{}{
+		"path": this.Path,
+		"type": this.Type.String(),
+	}
+	if this.Type != Added {
+		output["old"] = this.Old
+		output["oldKind"] = this.Old.kind.String()
+	}
+	if this.Type != Removed {
+		output["new"] = this.New
+		output["newKind"] = this.New.kind.String()
+	}
+	return json.Marshal(output)
+}
+
+func (this Value) equal(other Value) bool {
+	if this.kind != other.kind { return false }
+	switch this.kind {
+	case kindFloat:
+		// 0.1 and 1e-1 are the same float, and NaN is unchanged if it stays NaN
+		a, b := this.AsFloat64(), other.AsFloat64()
+		return a == b || (math.IsNaN(a) && math.IsNaN(b))
+	case kindDate, kindLocalDate, kindLocalTime, kindLocalDateTime:
+		// 07:32:00 and 07:32:00.000 are the same time
+		return this.scalar == other.scalar && this.nsec == other.nsec && this.zone == other.zone
+	case kindArray, kindTable:
+		if len(this.array) != len(other.array) { return false }
+		for i := range this.array {
+			if !this.array[i].equal(other.array[i]) { return false }
+		}
+		return true
+	}
+	return this.Reveal().String() == other.Reveal().String()
+}
+
+// Returns the values that were added, removed or modified in b compared to a,
+// sorted by key path.
+func Diff(a Document, b Document) []Change {
+	var output []Change
+	aValues := flattenValues(a)
+	bValues := flattenValues(b)
+	for path, aValue := range aValues {
+		bValue, ok := bValues[path]
+		if !ok {
+			output = append(output, Change{Path: path, Type: Removed, Old: aValue})
+		} else if !aValue.equal(bValue) {
+			output = append(output, Change{Path: path, Type: Modified, Old: aValue, New: bValue})
+		}
+	}
+	for path, bValue := range bValues {
+		if _, ok := aValues[path]; !ok { output = append(output, Change{Path: path, Type: Added, New: bValue}) }
+	}
+	sort.Sort(changesByPath(output))
+	return output
+}
+
+type changesByPath []Change
+
+func (this changesByPath) Len() int { return len(this) }
+func (this changesByPath) Less(i, j int) bool { return this[i].Path < this[j].Path }
+func (this changesByPath) Swap(i, j int) { this[i], this[j] = this[j], this[i] }
+
+func flattenValues(doc Document) map[string]Value {
+	output := make(map[string]Value)
+	doc.root.eachValue(func(node *Node) {
+		output[node.FullName()] = node.value
+	})
+	return output
+}
+
+// Renders the changes as text, one per line
+func FormatChanges(changes []Change) string {
+	lines := make([]string, len(changes))
+	for i, change := range changes {
+		lines[i] = change.String()
+	}
+	if len(lines) == 0 { return "" }
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Renders the changes as a JSON array
+func FormatChangesJSON(changes []Change) (string, error) {
+	if changes == nil { changes = []Change{} }
+	output, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil { return "", err }
+	return string(output) + "\n", nil
+}
@@ -0,0 +1,183 @@
+package toml
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Implemented by the types that encode themselves as a TOML value
+type Marshaler interface {
+	MarshalTOML() (Value, error)
+}
+
+var (
+	marshalerType = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	bigIntType = reflect.TypeOf(big.Int{})
+)
+
+// Returns the value, or a pointer to a copy of it, if either implements the
+// interface
+func implementation(source reflect.Value, interfaceType reflect.Type) interface{} {
+	if source.Type().Implements(interfaceType) { return source.Interface() }
+	if !reflect.PtrTo(source.Type()).Implements(interfaceType) { return nil }
+	if source.CanAddr() { return source.Addr().Interface() }
+	pointer := reflect.New(source.Type())
+	pointer.Elem().Set(source)
+	return pointer.Interface()
+}
+
+// Builds a document from a struct, or a pointer to a struct. The fields are
+// named like in Decode(), nested structs and maps become sections and nil
+// pointers are left out. Structs and maps in arrays become inline tables.
+// Durations and byte sizes are written as strings such as "1m30s" and "10MiB",
+// which Decode() reads back. Types that implement Marshaler encode themselves,
+// and the ones that implement encoding.TextMarshaler are written as strings.
+// The values of the fields with the sensitive option, eg.
+// `toml:"password,sensitive"`, are sensitive, see Sensitive().
+func Encode(v interface{}) (Document, error) {
+	source := reflect.ValueOf(v)
+	for source.Kind() == reflect.Ptr && !source.IsNil() { source = source.Elem() }
+	if source.Kind() != reflect.Struct || isDateType(source.Type()) { return Document{}, errors.New("toml: Encode needs a struct") }
+
+	builder := NewBuilder()
+	if err := encodeSection(builder, nil, source, false); err != nil { return Document{}, err }
+	return builder.Document(), nil
+}
+
+// Returns true if the value is encoded as a section or an inline table
+func isTableType(source reflect.Value) bool {
+	if source.Kind() != reflect.Struct && source.Kind() != reflect.Map { return false }
+	if isDateType(source.Type()) || source.Type() == bigIntType { return false }
+	return implementation(source, marshalerType) == nil && implementation(source, textMarshalerType) == nil
+}
+
+// A field of a struct or an entry of a map to encode
+type encodedField struct {
+	key string
+	value reflect.Value
+	sensitive bool
+}
+
+// Returns the fields of a struct, or the entries of a map sorted by key. Nil
+// pointers, interfaces and maps are left out.
+func encodeFields(source reflect.Value, path string) ([]encodedField, error) {
+	var output []encodedField
+	add := func(key string, field reflect.Value, sensitive bool) {
+		for (field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface) && !field.IsNil() { field = field.Elem() }
+		if (field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface || field.Kind() == reflect.Map) && field.IsNil() { return }
+		output = append(output, encodedField{key, field, sensitive})
+	}
+
+	if source.Kind() == reflect.Struct {
+		for _, structField := range structFields(source.Type()) {
+			if field, ok := fieldByIndex(source, structField.index, false); ok { add(structField.key, field, isSensitiveField(structField.field)) }
+		}
+		return output, nil
+	}
+
+	entries := make(map[string]reflect.Value)
+	var names []string
+	for _, key := range source.MapKeys() {
+		name := ""
+		if marshaler := implementation(key, textMarshalerType); marshaler != nil {
+			text, err := marshaler.(encoding.TextMarshaler).MarshalText()
+			if err != nil { return nil, fmt.Errorf("toml: %s: %w", path, err) }
+			name = string(text)
+		} else if key.Kind() == reflect.String {
+			name = key.String()
+		} else {
+			return nil, fmt.Errorf("toml: %s: cannot encode key of type %s", path, key.Type())
+		}
+		entries[name] = source.MapIndex(key)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names { add(name, entries[name], false) }
+	return output, nil
+}
+
+// Encodes a struct or a map as a section. All its values are sensitive if the
+// section is.
+func encodeSection(builder *Builder, names []string, source reflect.Value, sensitive bool) error {
+	builder.makeSections(names)
+	fields, err := encodeFields(source, strings.Join(names, "."))
+	if err != nil { return err }
+	for _, field := range fields {
+		path := append(names[0:len(names):len(names)], field.key)
+		if isTableType(field.value) {
+			if err := encodeSection(builder, path, field.value, sensitive || field.sensitive); err != nil { return err }
+			continue
+		}
+		value, err := encodeValue(field.value, strings.Join(path, "."))
+		if err != nil { return err }
+		if sensitive || field.sensitive { value = Sensitive(value) }
+		if err := builder.set(path, value); err != nil { return err }
+	}
+	return nil
+}
+
+func encodeValue(source reflect.Value, path string) (Value, error) {
+	if marshaler := implementation(source, marshalerType); marshaler != nil {
+		value, err := marshaler.(Marshaler).MarshalTOML()
+		if err != nil { return Value{}, fmt.Errorf("toml: %s: %w", path, err) }
+		return value, nil
+	}
+
+	switch source.Type() {
+	case timeType: return NewDate(source.Interface().(time.Time)), nil
+	case localDateType: return NewLocalDate(source.Interface().(LocalDate)), nil
+	case localTimeType: return NewLocalTime(source.Interface().(LocalTime)), nil
+	case localDateTimeType: return NewLocalDateTime(source.Interface().(LocalDateTime)), nil
+	case durationType: return NewString(time.Duration(source.Int()).String()), nil
+	case byteSizeType: return NewString(ByteSize(source.Int()).String()), nil
+	case bigIntType:
+		n := source.Interface().(big.Int)
+		return NewBigInt(&n), nil
+	}
+
+	if marshaler := implementation(source, textMarshalerType); marshaler != nil {
+		text, err := marshaler.(encoding.TextMarshaler).MarshalText()
+		if err != nil { return Value{}, fmt.Errorf("toml: %s: %w", path, err) }
+		return NewString(string(text)), nil
+	}
+
+	switch source.Kind() {
+	case reflect.Bool: return NewBool(source.Bool()), nil
+	case reflect.String: return NewString(source.String()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64: return NewInt(source.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NewBigInt(new(big.Int).SetUint64(source.Uint())), nil
+	case reflect.Float32, reflect.Float64: return NewFloat(source.Float()), nil
+	case reflect.Slice, reflect.Array:
+		array := make([]Value, source.Len())
+		for i := range array {
+			element := source.Index(i)
+			for (element.Kind() == reflect.Ptr || element.Kind() == reflect.Interface) && !element.IsNil() { element = element.Elem() }
+			value, err := encodeValue(element, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil { return Value{}, err }
+			array[i] = value
+		}
+		return NewArray(array...), nil
+	case reflect.Struct, reflect.Map:
+		fields, err := encodeFields(source, path)
+		if err != nil { return Value{}, err }
+		keys := make([]string, len(fields))
+		values := make([]Value, len(fields))
+		for i, field := range fields {
+			value, err := encodeValue(field.value, path + "." + field.key)
+			if err != nil { return Value{}, err }
+			if field.sensitive { value = Sensitive(value) }
+			keys[i] = field.key
+			values[i] = value
+		}
+		return NewTable(keys, values), nil
+	}
+	return Value{}, fmt.Errorf("toml: %s: cannot encode %s", path, source.Type())
+}
@@ -0,0 +1,97 @@
+package toml
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type EnvOptions struct {
+	Separator string // Separates the prefix and the key path components. Defaults to "_".
+	CaseSensitive bool // By default names are matched case-insensitively, so MYAPP_DATABASE_PORT matches database.port
+	Strict bool // Report the variables that start with the prefix but match no key. Requires a prefix, or ApplyEnv() fails.
+	Environ []string // "NAME=value" pairs to use instead of os.Environ()
+}
+
+type UnknownEnvError struct {
+	Names []string
+}
+
+func (this *UnknownEnvError) Error() string {
+	return "toml: unknown environment variables: " + strings.Join(this.Names, ", ")
+}
+
+func (this EnvOptions) fold(name string) string {
+	if this.CaseSensitive { return name }
+	return strings.ToUpper(name)
+}
+
+// Returns a copy of the document where the values are overridden by the
+// environment variables named after their key path. For example, with the
+// "MYAPP" prefix, MYAPP_DATABASE_PORT overrides the "port" key of the
+// [database] section. The variables are parsed like TOML values and must be of
+// the same kind as the value they override, except for strings which don't
+// need to be quoted. When names are matched case-insensitively, two variables
+// that only differ by case, such as MYAPP_PORT and myapp_port, are an error.
+// So is a variable whose name matches two keys, such as MYAPP_A_B_C for a.b_c
+// and a_b.c.
+func (this Document) ApplyEnv(prefix string, options EnvOptions) (Document, error) {
+	if options.Strict && prefix == "" { return this, errors.New("toml: strict environment variables need a prefix") }
+	separator := options.Separator
+	if separator == "" { separator = "_" }
+	environ := options.Environ
+	if environ == nil { environ = os.Environ() }
+
+	namePrefix := ""
+	if prefix != "" { namePrefix = options.fold(prefix + separator) }
+
+	nodes := make(map[string]*Node)
+	collisions := make(map[string]*Node) // The second key of the names shared by two keys
+	this.root.eachValue(func(node *Node) {
+		name := namePrefix + options.fold(strings.Join(node.path(), separator))
+		if _, exists := nodes[name]; exists {
+			collisions[name] = node
+		} else {
+			nodes[name] = node
+		}
+	})
+
+	values := make(map[*Node]Value)
+	names := make(map[*Node]string)
+	var unknown []string
+	for _, entry := range environ {
+		index := strings.Index(entry, "=")
+		if index <= 0 { continue }
+		name := entry[0:index]
+		node, ok := nodes[options.fold(name)]
+		if !ok {
+			if options.Strict && namePrefix != "" && strings.HasPrefix(options.fold(name), namePrefix) {
+				unknown = append(unknown, name)
+			}
+			continue
+		}
+		if other, exists := collisions[options.fold(name)]; exists { return this, fmt.Errorf("toml: environment variable %s matches both %s and %s", name, node.FullName(), other.FullName()) }
+		if other, exists := names[node]; exists && other != name {
+			if other > name { other, name = name, other }
+			return this, fmt.Errorf("toml: environment variables %s and %s both set %s", other, name, node.FullName())
+		}
+		names[node] = name
+		value, err := parseValueAs(entry[index + 1:], node.value.kind)
+		if err != nil && node.value.IsSensitive() { return this, fmt.Errorf("toml: environment variable %s: invalid %s value", name, node.value.kind) }
+		if err != nil { return this, fmt.Errorf("toml: environment variable %s: %s", name, err) }
+		values[node] = value
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return this, &UnknownEnvError{unknown}
+	}
+
+	builder := this.Builder()
+	for node, value := range values {
+		builder.set(node.path(), value)
+	}
+	return builder.Document(), nil
+}
@@ -0,0 +1,98 @@
+package toml
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ErrorCode int
+
+const (
+	ErrInvalidLine ErrorCode = 1 // Not a key, a section header or a comment
+	ErrInvalidHeader ErrorCode = 2
+	ErrInvalidKey ErrorCode = 3
+	ErrDuplicateKey ErrorCode = 4
+	ErrInvalidValue ErrorCode = 5
+	ErrIntegerOverflow ErrorCode = 6
+)
+
+func (this ErrorCode) String() string {
+	switch this {
+	case ErrInvalidLine: return "invalid-line"
+	case ErrInvalidHeader: return "invalid-header"
+	case ErrInvalidKey: return "invalid-key"
+	case ErrDuplicateKey: return "duplicate-key"
+	case ErrInvalidValue: return "invalid-value"
+	case ErrIntegerOverflow: return "integer-overflow"
+	}
+	return "error-" + strconv.Itoa(int(this))
+}
+
+// A problem found while parsing a document
+type Error struct {
+	Code ErrorCode
+	Position Position
+	Message string
+	line string // The source line, for the snippet
+}
+
+func (this *Error) Error() string {
+	return this.Position.String() + ": " + this.Message
+}
+
+// Returns the source line followed by a caret under the offending column:
+//
+//	port = 80x
+//	         ^
+func (this *Error) Snippet() string {
+	caret := ""
+	column := 1
+	for _, c := range this.line {
+		if column >= this.Position.Column { break }
+		if c == '\t' {
+			caret += "\t"
+		} else {
+			caret += " "
+		}
+		column++
+	}
+	return this.line + "\n" + caret + "^"
+}
+
+// The errors found in a document, sorted by position
+type ErrorList []*Error
+
+func (this ErrorList) Error() string {
+	switch len(this) {
+	case 0: return "no errors"
+	case 1: return this[0].Error()
+	}
+	return this[0].Error() + " (and " + strconv.Itoa(len(this) - 1) + " more errors)"
+}
+
+// Returns every error with its snippet
+func (this ErrorList) Report() string {
+	var output []string
+	for _, err := range this {
+		output = append(output, err.Error() + "\n" + err.Snippet())
+	}
+	return strings.Join(output, "\n")
+}
+
+func (this ErrorList) sort() {
+	sort.SliceStable(this, func(i, j int) bool { return this[i].Position.Offset < this[j].Position.Offset })
+}
+
+func (this *sourceFile) newError(code ErrorCode, offset int, end int, message string) *Error {
+	position := this.position(offset, end)
+	return &Error{code, position, message, this.line(position.Line)}
+}
+
+// Returns the text of the given line, starting at 1, without its line ending
+func (this *sourceFile) line(line int) string {
+	this.lines()
+	end := len(this.text)
+	if line < len(this.lineStarts) { end = this.lineStarts[line] - 1 }
+	return strings.TrimRight(this.text[this.lineStarts[line - 1]:end], "\r")
+}
@@ -10,8 +10,8 @@ import (
 // Automatically build the `Document::GetXXX()` functions
 
 func main() {
-	types := [...]string{"[]Value", "string", "int", "int8", "int16", "int32", "int64", "float", "float32", "float64", "bool", "time.Time"}
-	defaults := [...]string{"make([]Value, 0)", "\"\"", "0", "0", "0", "0", "0", "0.0", "0.0", "0.0", "false", "time.Now()"}
+	types := [...]string{"[]Value", "string", "int", "int8", "int16", "int32", "int64", "float", "float32", "float64", "bool", "time.Time", "LocalDate", "LocalTime", "LocalDateTime", "time.Duration", "ByteSize"}
+	defaults := [...]string{"make([]Value, 0)", "\"\"", "0", "0", "0", "0", "0", "0.0", "0.0", "0.0", "false", "time.Now()", "LocalDate{}", "LocalTime{}", "LocalDateTime{}", "0", "0"}
 	
 	output := ""
 	
@@ -27,6 +27,10 @@ func main() {
 			typeTitle = "Date"	
 		}
 		
+		if (typeName == "time.Duration") {
+			typeTitle = "Duration"
+		}
+		
 		if (typeName == "float") {
 			typeName = "float64"
 		}
@@ -0,0 +1,133 @@
+package toml
+
+import (
+	"errors"
+	"flag"
+	"reflect"
+	"strings"
+)
+
+// flag.Value bound to a document value. Set() checks that the new value is of
+// the same kind as the one in the document.
+type valueFlag struct {
+	node *Node
+	value Value
+}
+
+func (this *valueFlag) String() string {
+	if this == nil || this.value.kind == 0 { return "" }
+	return this.value.String()
+}
+
+func (this *valueFlag) Set(s string) error {
+	value, err := parseValueAs(s, this.node.value.kind)
+	if err != nil && this.node.value.IsSensitive() { return errors.New("invalid " + this.node.value.kind.String() + " value") }
+	if err != nil { return err }
+	if this.node.value.IsSensitive() { value = Sensitive(value) }
+	this.value = value
+	return nil
+}
+
+func (this *valueFlag) IsBoolFlag() bool {
+	return this.node != nil && this.node.value.kind == kindBool
+}
+
+// Registers a flag for each value of the document, named after its key path
+// (eg. -database.port). The default is the value in the document and the usage
+// text is the key comment. Flags that are already defined are left as they are.
+func (this Document) RegisterFlags(flags *flag.FlagSet) {
+	this.root.eachValue(func(node *Node) {
+		name := node.FullName()
+		if flags.Lookup(name) != nil { return }
+		usage := strings.Replace(node.comment, "\n", " ", -1)
+		flags.Var(&valueFlag{node, node.value}, name, usage)
+	})
+}
+
+// Returns a copy of the document with the values of the flags that have been
+// set on the command line. Only the flags registered with RegisterFlags() are
+// applied, and it's an error if one of them can't be, eg. because its key is a
+// section in this document.
+func (this Document) ApplyFlags(flags *flag.FlagSet) (Document, error) {
+	builder := this.Builder()
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		v, ok := f.Value.(*valueFlag)
+		if !ok || err != nil { return }
+		err = builder.set(v.node.path(), v.value)
+	})
+	if err != nil { return this, err }
+	return builder.Document(), nil
+}
+
+// flag.Value bound to a struct field. Set() parses the value with the kind of
+// the current value of the field, and decodes it into the field.
+type fieldFlag struct {
+	field reflect.Value
+	path string
+	value Value
+}
+
+func (this *fieldFlag) String() string {
+	if this == nil || this.value.kind == 0 { return "" }
+	return this.value.String()
+}
+
+func (this *fieldFlag) Set(s string) error {
+	value, err := parseValueAs(s, this.value.kind)
+	if err != nil && this.value.IsSensitive() { return errors.New("invalid " + this.value.kind.String() + " value") }
+	if err != nil { return err }
+	if this.value.IsSensitive() { value = Sensitive(value) }
+	if err := newDecoder(DecoderConfig{}).value(value, this.field, this.path); err != nil { return err }
+	this.value = value
+	return nil
+}
+
+func (this *fieldFlag) IsBoolFlag() bool {
+	return this != nil && this.value.kind == kindBool
+}
+
+// Registers a flag for each field of the struct that v points to, named after
+// its key path like in Decode() (eg. -database.port). The default is the
+// current value of the field, and setting the flag decodes the new value into
+// the field. The usage texts are taken from comments, keyed like the ones
+// returned by ParseComments(), which may be nil. Maps, interfaces and nil
+// pointers have no flag, and flags that are already defined are left as they
+// are.
+func RegisterStructFlags(flags *flag.FlagSet, v interface{}, comments map[string]string) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct || isDateType(target.Elem().Type()) {
+		return errors.New("toml: RegisterStructFlags needs a pointer to a struct")
+	}
+	return registerFieldFlags(flags, nil, target.Elem(), target.Elem().Type().Name(), comments, false)
+}
+
+func registerFieldFlags(flags *flag.FlagSet, names []string, target reflect.Value, name string, comments map[string]string, sensitive bool) error {
+	t := target.Type()
+	if t.Name() != "" { name = t.Name() }
+	for _, field := range structFields(t) {
+		fieldValue, ok := fieldByIndex(target, field.index, false)
+		if !ok { continue }
+		for fieldValue.Kind() == reflect.Ptr && !fieldValue.IsNil() { fieldValue = fieldValue.Elem() }
+		kind := fieldValue.Kind()
+		if kind == reflect.Ptr || kind == reflect.Interface || kind == reflect.Map { continue }
+
+		path := append(names[0:len(names):len(names)], field.key)
+		commentName := fieldCommentName(t, name, field.index)
+		isSensitive := sensitive || isSensitiveField(field.field)
+		if kind == reflect.Struct && isTableType(fieldValue) {
+			if err := registerFieldFlags(flags, path, fieldValue, commentName, comments, isSensitive); err != nil { return err }
+			continue
+		}
+
+		flagName := strings.Join(path, ".")
+		if flags.Lookup(flagName) != nil { continue }
+		value, err := encodeValue(fieldValue, flagName)
+		if err != nil { return err }
+		if isSensitive { value = Sensitive(value) }
+		usage := comments[commentName]
+		if usage == "" && fieldValue.Type().Name() != "" { usage = comments[fieldValue.Type().Name()] }
+		flags.Var(&fieldFlag{fieldValue, flagName, value}, flagName, strings.Replace(usage, "\n", " ", -1))
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package toml
+
+import (
+	"errors"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Import path of this package, for the generated code that uses LocalTime
+const importPath = "github.com/laurent22/toml-go/toml"
+
+// The Go type inferred from the values found at the same key of the samples
+type goShape struct {
+	kind Kind // 0 until a value is seen
+	mixed bool // Values of kinds that don't fit in one Go type
+	optional bool // Missing from some of the tables
+	keys []string
+	fields map[string]*goShape
+	tables int // Number of tables merged into the shape
+	element *goShape
+}
+
+func (this *goShape) add(v Value) {
+	if v.kind == 0 { return }
+	switch {
+	case this.kind == 0: this.kind = v.kind
+	case this.kind == v.kind:
+	case (this.kind == kindInt && v.kind == kindFloat) || (this.kind == kindFloat && v.kind == kindInt): this.kind = kindFloat
+	default: this.mixed = true
+	}
+
+	switch v.kind {
+	case kindArray:
+		if this.element == nil { this.element = &goShape{} }
+		for _, element := range v.array {
+			this.element.add(element)
+		}
+
+	case kindTable:
+		if this.fields == nil { this.fields = make(map[string]*goShape) }
+		seen := make(map[string]bool)
+		for i := 0; i + 1 < len(v.array); i += 2 {
+			key := v.array[i].text
+			field, ok := this.fields[key]
+			if !ok {
+				field = &goShape{optional: this.tables > 0}
+				this.fields[key] = field
+				this.keys = append(this.keys, key)
+			}
+			field.add(v.array[i + 1])
+			seen[key] = true
+		}
+		for key, field := range this.fields {
+			if !seen[key] { field.optional = true }
+		}
+		this.tables++
+	}
+}
+
+// Returns the Go type of the shape. Sections that are missing from some of
+// the samples become pointers, so that they are only allocated if they exist.
+func (this *goShape) goType(imports map[string]bool) string {
+	if this.mixed { return "interface{}" }
+	switch this.kind {
+	case kindBool: return "bool"
+	case kindString: return "string"
+	case kindInt: return "int64"
+	case kindFloat: return "float64"
+	case kindDate, kindLocalDate, kindLocalDateTime:
+		imports["time"] = true
+		return "time.Time"
+	case kindLocalTime:
+		imports[importPath] = true
+		return "toml.LocalTime"
+	case kindArray: return "[]" + this.element.goType(imports)
+	case kindTable:
+		output := "struct {\n"
+		names := make(map[string]bool)
+		for _, key := range this.keys {
+			field := this.fields[key]
+			name := goName(key)
+			for i := 2; names[name]; i++ { name = goName(key) + strconv.Itoa(i) }
+			names[name] = true
+			fieldType := field.goType(imports)
+			if field.optional && !field.mixed && field.kind == kindTable { fieldType = "*" + fieldType }
+			output += name + " " + fieldType + " `toml:\"" + key + "\"`\n"
+		}
+		return output + "}"
+	}
+	return "interface{}" // Big integers, and arrays that are always empty
+}
+
+var goInitialisms = map[string]bool{
+	"API": true, "CPU": true, "DNS": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true, "TTL": true, "UDP": true, "URI": true,
+	"URL": true, "UUID": true, "XML": true,
+}
+
+// Converts a key such as "max_conn-count" to an exported Go name such as
+// "MaxConnCount"
+func goName(key string) string {
+	words := strings.FieldsFunc(key, func(c rune) bool { return !unicode.IsLetter(c) && !unicode.IsDigit(c) })
+	output := ""
+	for _, word := range words {
+		if goInitialisms[strings.ToUpper(word)] {
+			output += strings.ToUpper(word)
+			continue
+		}
+		if plural := strings.TrimSuffix(word, "s"); plural != word && goInitialisms[strings.ToUpper(plural)] {
+			output += strings.ToUpper(plural) + "s"
+			continue
+		}
+		runes := []rune(word)
+		output += string(unicode.ToUpper(runes[0])) + string(runes[1:len(runes)])
+	}
+	if output == "" || !unicode.IsLetter([]rune(output)[0]) { output = "X" + output }
+	return output
+}
+
+// Generates the Go source of a struct type that the samples decode into, with
+// nested structs for the sections and inline tables, slices for the arrays and
+// arrays of tables, int64 and float64 for the numbers and time.Time for the
+// dates. Keys are merged across the samples, integers and floats found at the
+// same key become float64, and other mixed kinds interface{}.
+func GenerateStruct(packageName string, typeName string, samples ...Document) ([]byte, error) {
+	if len(samples) == 0 { return nil, errors.New("toml: no sample to generate a struct from") }
+	shape := &goShape{}
+	for _, sample := range samples {
+		if sample.root == nil { continue }
+		shape.add(sectionValue(sample.root))
+	}
+	if shape.kind == 0 { shape.kind = kindTable }
+
+	imports := make(map[string]bool)
+	body := "type " + typeName + " " + shape.goType(imports) + "\n"
+	output := "package " + packageName + "\n\n"
+	if len(imports) > 0 {
+		output += "import (\n"
+		if imports["time"] { output += "\t\"time\"\n" }
+		if imports["time"] && imports[importPath] { output += "\n" }
+		if imports[importPath] { output += "\t\"" + importPath + "\"\n" }
+		output += ")\n\n"
+	}
+	return format.Source([]byte(output + body))
+}
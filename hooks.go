@@ -0,0 +1,97 @@
+package toml
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Converts a value before it's decoded into a field of the given type. A hook
+// returns nil to leave the value to the next hooks and to Decode(), a Value to
+// replace it, or the decoded value, which must be assignable to the field.
+// Sections are given to the hooks as tables.
+type DecodeHook func(from Kind, to reflect.Type, v Value) (interface{}, error)
+
+var (
+	ipType = reflect.TypeOf(net.IP{})
+	ipNetType = reflect.TypeOf(net.IPNet{})
+	urlType = reflect.TypeOf(url.URL{})
+)
+
+// Decodes strings such as "1m30s" into time.Duration, and numbers as a number
+// of the given unit, eg. 1.5 is 1.5s if the unit is time.Second
+func DurationHook(unit time.Duration) DecodeHook {
+	return func(from Kind, to reflect.Type, v Value) (interface{}, error) {
+		if to != durationType { return nil, nil }
+		switch from {
+		case KindString: return v.Duration()
+		case KindInt, KindFloat:
+			f := float64(v.AsInt64())
+			if from == KindFloat { f = v.AsFloat64() }
+			f *= float64(unit)
+			if math.IsNaN(f) || f >= math.MaxInt64 || f <= math.MinInt64 { return nil, &RangeError{v.String(), "time.Duration"} }
+			return time.Duration(math.Round(f)), nil
+		}
+		return nil, nil
+	}
+}
+
+// Decodes strings into net.IP, and CIDR strings ("10.0.0.0/8") or arrays of an
+// address and a mask (["10.0.0.0", "255.0.0.0"]) into net.IPNet
+func IPHook(from Kind, to reflect.Type, v Value) (interface{}, error) {
+	switch {
+	case to == ipType && from == KindString:
+		ip := net.ParseIP(v.AsString())
+		if ip == nil { return nil, fmt.Errorf("invalid IP address %s", v) }
+		return ip, nil
+
+	case to == ipNetType && from == KindString:
+		_, network, err := net.ParseCIDR(v.AsString())
+		if err != nil { return nil, fmt.Errorf("invalid CIDR address %s", v) }
+		return *network, nil
+
+	case to == ipNetType && from == KindArray:
+		array := v.AsArray()
+		if len(array) != 2 { return nil, fmt.Errorf("expected an address and a mask, got %s", v) }
+		ip := net.ParseIP(array[0].AsString())
+		mask := net.ParseIP(array[1].AsString())
+		if ip == nil || mask == nil { return nil, fmt.Errorf("invalid address or mask %s", v) }
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, mask = ip4, mask.To4()
+			if mask == nil { return nil, fmt.Errorf("invalid mask %s for an IPv4 address", array[1]) }
+		}
+		return net.IPNet{IP: ip.Mask(net.IPMask(mask)), Mask: net.IPMask(mask)}, nil
+	}
+	return nil, nil
+}
+
+// Decodes strings into url.URL and *url.URL
+func URLHook(from Kind, to reflect.Type, v Value) (interface{}, error) {
+	if from != KindString || (to != urlType && to != reflect.PtrTo(urlType)) { return nil, nil }
+	output, err := url.Parse(v.AsString())
+	if err != nil && v.IsSensitive() { return nil, fmt.Errorf("invalid URL %s", v) }
+	if err != nil { return nil, err }
+	if to == urlType { return *output, nil }
+	return output, nil
+}
+
+// Splits strings such as "a, b, c" into arrays for the slice fields. The parts
+// are parsed like environment variables, so "1, 2" is an array of integers.
+func SliceHook(separator string) DecodeHook {
+	return func(from Kind, to reflect.Type, v Value) (interface{}, error) {
+		if from != KindString || to.Kind() != reflect.Slice || to == ipType { return nil, nil }
+		if v.AsString() == "" { return NewArray(), nil }
+		parts := strings.Split(v.AsString(), separator)
+		array := make([]Value, len(parts))
+		for i, part := range parts {
+			element, err := parseValueAs(part, 0)
+			if err != nil { return nil, err }
+			array[i] = element
+		}
+		return NewArray(array...), nil
+	}
+}
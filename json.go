@@ -0,0 +1,59 @@
+package toml
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// Encodes the value as the closest JSON type. Dates are encoded as RFC 3339
+// strings, or their date and time parts for local ones, and infinities and NaN as "inf", "-inf" and "nan".
+// Sensitive values are encoded as "[redacted]".
+func (this Value) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.jsonValue())
+}
+
+func (this Value) jsonValue() interface{} {
+	if this.flags & flagSensitive != 0 { return redacted }
+	switch this.kind {
+	case kindBool: return this.AsBool()
+	case kindString: return this.AsString()
+	case kindInt: return this.AsInt64()
+	case kindBigInt: return json.Number(this.text)
+	case kindFloat:
+		f := this.AsFloat64()
+		if math.IsInf(f, 0) || math.IsNaN(f) { return formatFloat(f) }
+		return f
+	case kindDate, kindLocalDate, kindLocalTime, kindLocalDateTime: return this.formatDate()
+	case kindArray:
+		output := make([]interface{}, len(this.array))
+		for i, v := range this.array {
+			output[i] = v.jsonValue()
+		}
+		return output
+	case kindTable:
+		output := make(map[string]interface{})
+		for i := 0; i + 1 < len(this.array); i += 2 {
+			output[this.array[i].text] = this.array[i + 1].jsonValue()
+		}
+		return output
+	}
+	return nil
+}
+
+func (this *Node) jsonValue() interface{} {
+	if this.kind == kindValue { return this.value.jsonValue() }
+	output := make(map[string]interface{})
+	for _, node := range this.order {
+		output[node.name] = node.jsonValue()
+	}
+	return output
+}
+
+// Encodes the section as a JSON object, or the value as the closest JSON type
+func (this *Node) MarshalJSON() ([]byte, error) {
+	return json.Marshal(this.jsonValue())
+}
+
+func (this Document) MarshalJSON() ([]byte, error) {
+	return this.root.MarshalJSON()
+}
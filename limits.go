@@ -0,0 +1,54 @@
+package toml
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Returned, or panicked by Parse(), when a document exceeds one of the limits
+// set on the Parser. Parsing stops at the first limit that is exceeded.
+type LimitError struct {
+	Limit string // Name of the Parser field, eg. "MaxDepth"
+	Max int
+	Position Position // Only the file is set for MaxInputSize
+}
+
+func (this *LimitError) Error() string {
+	message := fmt.Sprintf("%s of %d exceeded", this.Limit, this.Max)
+	if this.Position.IsValid() { return this.Position.String() + ": " + message }
+	if this.Position.File != "" { return this.Position.File + ": " + message }
+	return message
+}
+
+func (this *parseState) exceeded(limit string, max int, value int, offset int, end int) bool {
+	if max <= 0 || value <= max || this.limit != nil { return this.limit != nil }
+	this.limit = &LimitError{limit, max, this.source.position(offset, end)}
+	return true
+}
+
+// Adds a key or section to the document
+func (this *parseState) attach(parent *Node, node *Node) bool {
+	this.keys++
+	if this.exceeded("MaxKeys", this.parser.MaxKeys, this.keys, node.offset, node.end) { return false }
+	if parent.order == nil { parent.order = this.newChildren() }
+	parent.setChild(node.name, node)
+	return true
+}
+
+// Reads the file, without reading more than MaxInputSize bytes
+func (this Parser) readFile(path string) (string, error) {
+	if this.MaxInputSize <= 0 {
+		content, err := ioutil.ReadFile(path)
+		return string(content), err
+	}
+
+	file, err := os.Open(path)
+	if err != nil { return "", err }
+	defer file.Close()
+	content, err := ioutil.ReadAll(io.LimitReader(file, int64(this.MaxInputSize) + 1))
+	if err != nil { return "", err }
+	if len(content) > this.MaxInputSize { return "", &LimitError{"MaxInputSize", this.MaxInputSize, Position{File: path}} }
+	return string(content), nil
+}
@@ -0,0 +1,178 @@
+package toml
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The keys of a decoded document or section, and which of them were decoded.
+// The keys are named by their full path, as returned by Node.FullName(). The
+// keys of inline tables and of arrays of tables are included, with the index
+// of array elements, eg. point.x or fruits[0].name.
+type MetaData struct {
+	node *Node // The decoded section
+	decoded map[*Node]bool
+	keys map[string]bool // Decoded keys of the tables in values
+	structs map[string]bool // Tables in values that were decoded into structs
+}
+
+// A key or section that matches no field
+type undecodedKey struct {
+	path string
+	position Position
+	section bool
+}
+
+// Returns the kind of the value or section at the given path
+func (this MetaData) find(path string) (Kind, bool) {
+	if this.node == nil { return 0, false }
+	current := this.node
+	for current.parent != nil { current = current.parent }
+	var value Value
+	inValue := false
+	for _, part := range strings.Split(path, ".") {
+		name, indexes, ok := splitIndexes(part)
+		if !ok { return 0, false }
+		if inValue {
+			value, ok = value.Get(name)
+			if !ok { return 0, false }
+		} else {
+			child, ok := current.Child(name)
+			if !ok { return 0, false }
+			current = child
+			if child.kind == kindValue {
+				value = child.value
+				inValue = true
+			} else if len(indexes) > 0 {
+				return 0, false
+			}
+		}
+		for _, index := range indexes {
+			if value.kind != kindArray || index >= len(value.array) { return 0, false }
+			value = value.array[index]
+		}
+	}
+	if inValue { return value.kind, true }
+	return current.kind, true
+}
+
+// Splits a path component such as fruits[0][1] into its name and indexes
+func splitIndexes(part string) (string, []int, bool) {
+	start := strings.IndexByte(part, '[')
+	if start < 0 { return part, nil, true }
+	name := part[0:start]
+	var indexes []int
+	for rest := part[start:len(part)]; rest != ""; {
+		end := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || end < 0 { return name, nil, false }
+		index, err := strconv.Atoi(rest[1:end])
+		if err != nil || index < 0 { return name, nil, false }
+		indexes = append(indexes, index)
+		rest = rest[end + 1:len(rest)]
+	}
+	return name, indexes, true
+}
+
+// Returns true if the document has a key or section at this path
+func (this MetaData) IsDefined(path string) bool {
+	_, ok := this.find(path)
+	return ok
+}
+
+// Returns the kind of the value at this path. The kind of a section is the
+// one whose String() is "section", and it's zero, whose String() is
+// "undefined", if the document has nothing at this path.
+func (this MetaData) Kind(path string) Kind {
+	kind, _ := this.find(path)
+	return kind
+}
+
+// Returns the keys and sections of the decoded section, in declaration order
+func (this MetaData) Keys() []string {
+	var output []string
+	var add func(node *Node)
+	add = func(node *Node) {
+		for _, child := range node.Children() {
+			output = append(output, child.FullName())
+			if child.kind == kindValue {
+				output = valueKeys(child.value, child.FullName(), output)
+			} else {
+				add(child)
+			}
+		}
+	}
+	if this.node != nil { add(this.node) }
+	return output
+}
+
+// Appends the keys of the tables in the value, at any depth
+func valueKeys(v Value, path string, output []string) []string {
+	if v.kind == kindArray {
+		for i, element := range v.array {
+			output = valueKeys(element, path + "[" + strconv.Itoa(i) + "]", output)
+		}
+	} else if v.kind == kindTable {
+		for i := 0; i + 1 < len(v.array); i += 2 {
+			keyPath := joinPath(path, v.array[i].text)
+			output = append(output, keyPath)
+			output = valueKeys(v.array[i + 1], keyPath, output)
+		}
+	}
+	return output
+}
+
+// Returns the keys and sections that match no field, in declaration order. The
+// keys of a section that wasn't decoded are not listed, only the section.
+func (this MetaData) Undecoded() []string {
+	var output []string
+	if this.node == nil { return output }
+	for _, key := range this.undecoded(this.node, nil) {
+		output = append(output, key.path)
+	}
+	return output
+}
+
+// Returns the error of the Strict option for the first key or section that
+// matches no field, if any
+func (this MetaData) strictError() error {
+	if this.node == nil { return nil }
+	undecoded := this.undecoded(this.node, nil)
+	if len(undecoded) == 0 { return nil }
+	kind := "key"
+	if undecoded[0].section { kind = "section" }
+	return decodeError(undecoded[0].path, undecoded[0].position, "unknown %s", kind)
+}
+
+func (this MetaData) undecoded(node *Node, output []undecodedKey) []undecodedKey {
+	for _, child := range node.Children() {
+		if !this.decoded[child] {
+			output = append(output, undecodedKey{child.FullName(), child.Position(), child.kind == kindSection})
+		} else if child.kind == kindValue {
+			output = this.undecodedValues(child.value, child.FullName(), output)
+		} else {
+			output = this.undecoded(child, output)
+		}
+	}
+	return output
+}
+
+// Appends the keys of the tables in the value that match no field. Only the
+// tables decoded into structs have such keys, since maps and interface{} take
+// every key.
+func (this MetaData) undecodedValues(v Value, path string, output []undecodedKey) []undecodedKey {
+	if v.kind == kindArray {
+		for i, element := range v.array {
+			output = this.undecodedValues(element, path + "[" + strconv.Itoa(i) + "]", output)
+		}
+	} else if v.kind == kindTable && this.structs[path] {
+		for i := 0; i + 1 < len(v.array); i += 2 {
+			keyPath := joinPath(path, v.array[i].text)
+			if !this.keys[keyPath] {
+				output = append(output, undecodedKey{keyPath, v.array[i + 1].Position(), false})
+			} else {
+				output = this.undecodedValues(v.array[i + 1], keyPath, output)
+			}
+		}
+	}
+	return output
+}
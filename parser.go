@@ -0,0 +1,675 @@
+package toml
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The parser reads the document once, byte by byte. Values are parsed where
+// they are, including multi-line arrays, and strings without escape sequences
+// are slices of the document rather than copies.
+type parseState struct {
+	parser Parser
+	source *sourceFile // nil when parsing a single value
+	retained *sourceFile // Set on nodes and values, nil if the parser discards the source
+	text string
+	errors ErrorList
+	limit *LimitError
+	declared map[*Node]bool // The sections that have their own header
+	tables map[*Node][]*Node // The tables of each [[array of tables]], as detached sections
+	tableArrays []*Node // The arrays of tables, in declaration order
+	keys int // Number of keys and sections
+	stack []Value // Elements of the arrays and tables being parsed
+	values []Value // Slab that holds the elements of the arrays and tables
+	nodes []Node // Slab that holds the nodes
+	children []*Node // Slab that holds the first children of the sections
+	sectionDepth int // Depth of the current section
+	comment string // Trailing comment found on the line of the current key
+	newline bool // Whether the current value spans several lines so far
+	unterminated bool // Whether the last value that failed is missing its end
+	overflow bool // Whether the last value that failed is an integer out of range
+}
+
+func (this *parseState) addError(code ErrorCode, offset int, end int, format string, args ...interface{}) {
+	this.errors = append(this.errors, this.source.newError(code, offset, end, fmt.Sprintf(format, args...)))
+}
+
+// Returns an ErrorList, a *LimitError or nil
+func (this Parser) parse(tomlString string, fileName string) (Document, error) {
+	output := newDocument()
+	if this.MaxInputSize > 0 && len(tomlString) > this.MaxInputSize { return output, &LimitError{"MaxInputSize", this.MaxInputSize, Position{File: fileName}} }
+
+	state := &parseState{parser: this, source: newSourceFile(fileName, tomlString), text: tomlString, declared: make(map[*Node]bool), stack: make([]Value, 0, 16)}
+	if !this.DiscardSource { state.retained = state.source }
+	state.document(output.root)
+	state.finishTables()
+
+	if state.limit != nil { return output, state.limit }
+	if len(state.errors) == 0 { return output, nil }
+	state.errors.sort()
+	if !this.AllErrors { state.errors = state.errors[0:1] }
+	return output, state.errors
+}
+
+func (this *parseState) document(root *Node) {
+	text := this.text
+	section := root
+	var comments []string // Reused from a key to the next
+	for pos := 0; pos < len(text); {
+		if (len(this.errors) > 0 && !this.parser.AllErrors) || this.limit != nil { return }
+
+		start := skipSpaces(text, pos)
+		end := this.lineEnd(start)
+		line := trimSpacesRight(text[start:end])
+		pos = end + 1
+		if len(line) == 0 {
+			comments = comments[0:0]
+			continue
+		}
+
+		// COMMENT
+
+		if line[0] == '#' {
+			comments = append(comments, strings.Trim(line[1:len(line)], " \t"))
+			continue
+		}
+
+		// SECTION
+
+		if header, comment, ok := splitHeader(line); ok {
+			if comment != "" { comments = append(comments, comment) }
+			node, ok := this.parseHeader(root, header, start)
+			if !ok {
+				// The keys up to the next header go to a detached section, so
+				// that their errors are still reported.
+				node = this.newNode()
+				node.kind = kindSection
+			}
+			if node.comment == "" { node.comment = strings.Join(comments, "\n") }
+			comments = comments[0:0]
+			section = node
+			continue
+		}
+
+		// VALUE
+
+		equal := strings.IndexByte(line, '=')
+		if equal < 0 {
+			if line[0] == '[' {
+				this.addError(ErrInvalidHeader, start, start + len(line), "missing ] at the end of the section header")
+			} else {
+				this.addError(ErrInvalidLine, start, start + len(line), "expected a key, a section header or a comment")
+			}
+			comments = comments[0:0]
+			continue
+		}
+
+		key := trimSpacesRight(line[0:equal])
+		if this.exceeded("MaxKeyLength", this.parser.MaxKeyLength, len(key), start, start + len(key)) { return }
+		node := this.newNode()
+		node.name = key
+		node.kind = kindValue
+		node.source = this.retained
+		node.offset = start
+		node.end = start + len(key)
+		if key == "" {
+			this.addError(ErrInvalidKey, start, start + 1, "missing key before =")
+		} else if existing, exists := section.Child(key); exists {
+			this.addError(ErrDuplicateKey, start, start + len(key), "%s is already defined", existing.FullName())
+		} else if !this.attach(section, node) {
+			return
+		}
+
+		pos = this.keyValue(node, start + equal + 1)
+		if this.comment != "" { comments = append(comments, this.comment) }
+		node.comment = strings.Join(comments, "\n")
+		comments = comments[0:0]
+	}
+}
+
+func (this *parseState) parseHeader(root *Node, header string, start int) (*Node, bool) {
+	end := start + len(header)
+	isArray := len(header) >= 4 && header[1] == '[' && header[len(header) - 2] == ']'
+	names := strings.Split(header[1:len(header) - 1], ".")
+	if isArray { names = strings.Split(header[2:len(header) - 2], ".") }
+	for _, name := range names {
+		if strings.Trim(name, " \t") == "" {
+			this.addError(ErrInvalidHeader, start, end, "empty section name in %s", header)
+			return nil, false
+		}
+		if this.exceeded("MaxKeyLength", this.parser.MaxKeyLength, len(name), start, end) { return nil, false }
+	}
+	if this.exceeded("MaxDepth", this.parser.MaxDepth, len(names), start, end) { return nil, false }
+	this.sectionDepth = len(names)
+
+	current := root
+	for i, name := range names {
+		node, ok := current.Child(name)
+		last := i == len(names) - 1
+		if tables, isTables := this.tables[node]; ok && isTables && !last {
+			// [fruits.physical] is in the last table of [[fruits]]
+			current = tables[len(tables) - 1]
+			continue
+		} else if ok && isTables && !isArray {
+			this.addError(ErrDuplicateKey, start, end, "%s is already defined as an array of tables", node.FullName())
+			return nil, false
+		} else if ok && isTables {
+			return this.addTable(node, start, end)
+		} else if ok && isArray && last {
+			this.addError(ErrDuplicateKey, start, end, "%s is already defined", node.FullName())
+			return nil, false
+		} else if !ok && isArray && last {
+			node = this.newNode()
+			node.name = name
+			node.kind = kindValue
+			node.source = this.retained
+			node.offset = start
+			node.end = end
+			if !this.attach(current, node) { return nil, false }
+			this.tableArrays = append(this.tableArrays, node)
+			return this.addTable(node, start, end)
+		} else if !ok {
+			node = this.newNode()
+			node.name = name
+			node.kind = kindSection
+			node.source = this.retained
+			node.offset = start
+			node.end = end
+			if !this.attach(current, node) { return nil, false }
+		} else if node.kind != kindSection {
+			this.addError(ErrDuplicateKey, start, end, "%s is already defined as a key", node.FullName())
+			return nil, false
+		}
+		current = node
+	}
+
+	if this.declared[current] { this.addError(ErrDuplicateKey, start, end, "section [%s] is already defined", current.FullName()) }
+	this.declared[current] = true
+	current.offset = start
+	current.end = end
+	return current, true
+}
+
+// Starts a new table of an array of tables. The table is a detached section,
+// converted into a value at the end of the document.
+func (this *parseState) addTable(array *Node, start int, end int) (*Node, bool) {
+	this.keys++
+	if this.exceeded("MaxKeys", this.parser.MaxKeys, this.keys, start, end) { return nil, false }
+	if this.exceeded("MaxArrayLength", this.parser.MaxArrayLength, len(this.tables[array]) + 1, start, end) { return nil, false }
+	table := this.newNode()
+	table.name = array.name
+	table.kind = kindSection
+	table.parent = array.parent
+	table.source = this.retained
+	table.offset = start
+	table.end = end
+	if this.tables == nil { this.tables = make(map[*Node][]*Node) }
+	this.tables[array] = append(this.tables[array], table)
+	return table, true
+}
+
+// Sets the value of the arrays of tables. The nested arrays are declared after
+// the arrays that contain them, so they are set first.
+func (this *parseState) finishTables() {
+	for i := len(this.tableArrays) - 1; i >= 0; i-- {
+		array := this.tableArrays[i]
+		tables := this.tables[array]
+		values := make([]Value, len(tables))
+		for j, table := range tables {
+			values[j] = sectionValue(table)
+		}
+		array.value = NewArray(values...)
+	}
+}
+
+// Parses the value that starts at pos, and the rest of its last line. Returns
+// the start of the next line.
+func (this *parseState) keyValue(node *Node, pos int) int {
+	text := this.text
+	start := skipSpaces(text, pos)
+	this.comment = ""
+	this.newline = false
+	this.unterminated = false
+	this.overflow = false
+	node.value.source = this.retained
+	node.value.offset = uint32(start)
+	node.value.end = uint32(start)
+
+	if start >= len(text) || text[start] == '\n' || text[start] == '#' {
+		this.addError(ErrInvalidValue, start, start, "missing value for %s", node.name)
+		return this.lineRest(start)
+	}
+
+	v, index, ok := this.value(text[start:len(text)], start, this.sectionDepth)
+	if this.limit != nil { return len(text) }
+	if !ok && this.overflow {
+		this.addError(ErrIntegerOverflow, start + index, this.lineEnd(start + index), "integer out of range for %s, use Parser.BigIntegers to keep it", node.name)
+		return this.skipValue(start)
+	}
+	if !ok && this.unterminated {
+		this.addError(ErrInvalidValue, start, start + index, "unterminated value for %s", node.name)
+		return this.skipValue(start)
+	}
+	if !ok {
+		this.addError(ErrInvalidValue, start + index, start + index + 1, "invalid value for %s", node.name)
+		return this.skipValue(start)
+	}
+
+	node.value = v
+	rest := skipSpaces(text, start + index)
+	if rest < len(text) && text[rest] != '\n' && text[rest] != '#' {
+		this.addError(ErrInvalidValue, rest, this.lineEnd(rest), "unexpected text after the value of %s", node.name)
+	}
+	return this.lineRest(rest)
+}
+
+// Returns the start of the next line, after recording the comment that ends
+// the current one, if any
+func (this *parseState) lineRest(pos int) int {
+	end := this.lineEnd(pos)
+	if pos < end && this.text[pos] == '#' && this.comment == "" { this.comment = strings.Trim(this.text[pos + 1:end], " \t\r") }
+	return end + 1
+}
+
+// Returns the start of the line after the invalid value at pos. The lines of a
+// multi-line array are skipped, up to the next key or section header.
+func (this *parseState) skipValue(pos int) int {
+	end := this.lineEnd(pos)
+	depth := bracketDepth(blankComments(this.text[pos:end]))
+	for depth > 0 && end < len(this.text) && !isBoundary(this.text[end + 1:len(this.text)]) {
+		next := end + 1
+		end = this.lineEnd(next)
+		depth += bracketDepth(blankComments(this.text[next:end]))
+	}
+	return end + 1
+}
+
+// Parses the value at the start of s, which is at offset in the document, in a
+// section or array at the given depth. On error, returns the index of the
+// offending character.
+func (this *parseState) value(s string, offset int, depth int) (Value, int, bool) {
+	var v Value
+	if len(s) == 0 { return v, 0, false }
+
+	index := 0
+	ok := false
+	c := s[0]
+	if c == '"' {
+		v.kind = kindString
+		v.text, index, ok = this.string(s, offset)
+	} else if c == '[' {
+		v.kind = kindArray
+		v.array, index, ok = this.array(s, offset, depth + 1)
+	} else if c == '{' {
+		v.kind = kindTable
+		v.array, index, ok = this.table(s, offset, depth + 1)
+	} else if strings.HasPrefix(s, "true") {
+		v.kind = kindBool
+		v.scalar = 1
+		index, ok = 4, true
+	} else if strings.HasPrefix(s, "false") {
+		v.kind = kindBool
+		index, ok = 5, true
+	} else if isDateTime(s) {
+		v, index, ok = parseDateTime(s)
+	} else {
+		var number string
+		number, index, ok = parseNumber(s)
+		if ok && isInteger(number) {
+			parsed, err := strconv.ParseInt(number, 10, 64)
+			if err == nil {
+				v.kind = kindInt
+				v.scalar = uint64(parsed)
+			} else if err.(*strconv.NumError).Err != strconv.ErrRange {
+				index, ok = 0, false
+			} else if bigInt, isBig := new(big.Int).SetString(number, 10); isBig && this.parser.BigIntegers {
+				v.kind = kindBigInt
+				v.text = bigInt.String()
+			} else {
+				this.overflow = true
+				index, ok = 0, false
+			}
+		} else if ok {
+			parsed, err := strconv.ParseFloat(strings.TrimLeft(number, "+-"), 64)
+			if number[0] == '-' { parsed = -parsed }
+			v.kind = kindFloat
+			v.scalar = math.Float64bits(parsed)
+			v.text = this.retain(number)
+			if err != nil || !isFloat(number) { index, ok = 0, false }
+		}
+	}
+	if !ok { return Value{}, index, false }
+
+	v.source = this.retained
+	v.offset = uint32(offset)
+	v.end = uint32(offset + index)
+	return v, index, true
+}
+
+func (this *parseState) array(s string, offset int, depth int) ([]Value, int, bool) {
+	if this.exceeded("MaxDepth", this.parser.MaxDepth, depth, offset, offset + 1) { return nil, 0, false }
+
+	base := len(this.stack)
+	expectValue := true
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case ' ', '\t', '\r':
+			continue
+		case '\n':
+			this.newline = true
+			// In a document, a key or section header ends an unterminated array
+			if this.source != nil && isBoundary(s[i + 1:len(s)]) {
+				this.unterminated = true
+				return this.popValues(base), i, false
+			}
+			continue
+		case '#':
+			end := strings.IndexByte(s[i:len(s)], '\n')
+			if end < 0 { end = len(s) - i }
+			if !this.newline && this.comment == "" { this.comment = strings.Trim(s[i + 1:i + end], " \t\r") }
+			i += end - 1
+			continue
+		case ']':
+			return this.popValues(base), i + 1, true
+		}
+
+		if !expectValue {
+			if c != ',' { return this.popValues(base), i, false }
+			expectValue = true
+			continue
+		}
+
+		if this.exceeded("MaxArrayLength", this.parser.MaxArrayLength, len(this.stack) - base + 1, offset + i, offset + i + 1) { return this.popValues(base), i, false }
+		v, index, ok := this.value(s[i:len(s)], offset + i, depth)
+		if !ok { return this.popValues(base), i + index, false }
+		this.stack = append(this.stack, v)
+		i += index - 1
+		expectValue = false
+	}
+
+	this.unterminated = true
+	return this.popValues(base), len(s), false
+}
+
+// Parses an inline table, { name = "a", port = 80 }, as its keys and values,
+// alternating. Inline tables must be on a single line.
+func (this *parseState) table(s string, offset int, depth int) ([]Value, int, bool) {
+	if this.exceeded("MaxDepth", this.parser.MaxDepth, depth, offset, offset + 1) { return nil, 0, false }
+
+	i := skipSpaces(s, 1)
+	if i < len(s) && s[i] == '}' { return nil, i + 1, true }
+	base := len(this.stack)
+	names := make(map[string]bool)
+	for i < len(s) && s[i] != '\n' {
+		start := i
+		for i < len(s) {
+			c, size := utf8.DecodeRuneInString(s[i:len(s)])
+			if c == '.' || !isNameRune(c) { break }
+			i += size
+		}
+		key := s[start:i]
+		if key == "" { return this.popValues(base), i, false }
+		if this.exceeded("MaxKeyLength", this.parser.MaxKeyLength, len(key), offset + start, offset + i) { return this.popValues(base), i, false }
+		this.keys++
+		if this.exceeded("MaxKeys", this.parser.MaxKeys, this.keys, offset + start, offset + i) { return this.popValues(base), i, false }
+		if names[key] { return this.popValues(base), start, false }
+		names[key] = true
+		i = skipSpaces(s, i)
+		if i >= len(s) || s[i] != '=' { return this.popValues(base), i, false }
+		i = skipSpaces(s, i + 1)
+
+		v, index, ok := this.value(s[i:len(s)], offset + i, depth)
+		if !ok { return this.popValues(base), i + index, false }
+		this.stack = append(this.stack, NewString(this.retain(key)), v)
+		i = skipSpaces(s, i + index)
+		if i < len(s) && s[i] == '}' { return this.popValues(base), i + 1, true }
+		if i >= len(s) || s[i] != ',' { break }
+		i = skipSpaces(s, i + 1)
+	}
+
+	if i >= len(s) || s[i] == '\n' || s[i] == '#' {
+		this.unterminated = true
+		return this.popValues(base), i, false
+	}
+	return this.popValues(base), i, false
+}
+
+// Pops the values pushed on the stack since base, and returns them moved to
+// the slab, or nil if there are none. Allocating the elements of the arrays
+// and tables together saves most of the allocations of a document.
+func (this *parseState) popValues(base int) []Value {
+	values := this.stack[base:len(this.stack)]
+	this.stack = this.stack[0:base]
+	if len(values) == 0 { return nil }
+	if cap(this.values) - len(this.values) < len(values) {
+		size := 2 * cap(this.values)
+		if size < 16 { size = 16 }
+		if size > 1024 { size = 1024 }
+		if size < len(values) { size = len(values) }
+		this.values = make([]Value, 0, size)
+	}
+	start := len(this.values)
+	this.values = append(this.values, values...)
+	return this.values[start:len(this.values):len(this.values)]
+}
+
+// Returns an empty slice from the slab for the children of a section, which
+// has room for its first few children
+func (this *parseState) newChildren() []*Node {
+	const size = 4
+	if cap(this.children) - len(this.children) < size {
+		count := 2 * cap(this.children) / size
+		if count < 4 { count = 4 }
+		if count > 64 { count = 64 }
+		this.children = make([]*Node, 0, count * size)
+	}
+	start := len(this.children)
+	this.children = this.children[0:start + size]
+	return this.children[start:start:start + size]
+}
+
+// Returns a new node from the slab
+func (this *parseState) newNode() *Node {
+	if len(this.nodes) == cap(this.nodes) {
+		size := 2 * cap(this.nodes)
+		if size == 0 { size = len(this.text) / 64 } // About one key per line
+		if size < 8 { size = 8 }
+		if size > 256 { size = 256 }
+		this.nodes = make([]Node, 0, size)
+	}
+	this.nodes = this.nodes[0:len(this.nodes) + 1]
+	return &this.nodes[len(this.nodes) - 1]
+}
+
+func (this *parseState) string(s string, offset int) (string, int, bool) {
+	if len(s) <= 0 || s[0] != '"' { return "", 0, false }
+
+	// Find the end first, so that the length is checked before anything is
+	// allocated, and so that strings without escape sequences can be sliced.
+	end := -1
+	escaped := false
+	i := 1
+	for ; i < len(s); i++ {
+		c := s[i]
+		if c == '"' {
+			end = i
+			break
+		}
+		if c == '\n' { break }
+		if c == '\\' {
+			escaped = true
+			if i + 1 < len(s) && s[i + 1] != '\n' { i++ }
+		}
+	}
+
+	if this.exceeded("MaxStringLength", this.parser.MaxStringLength, i - 1, offset, offset + i + 1) { return "", 0, false }
+	if end < 0 {
+		this.unterminated = true
+		return "", i, false
+	}
+	if !escaped { return this.retain(s[1:end]), end + 1, true }
+
+	var output strings.Builder
+	output.Grow(end - 1)
+	last := 1
+	for i := 1; i < end; i++ {
+		if s[i] != '\\' { continue }
+		output.WriteString(s[last:i])
+		switch s[i + 1] {
+		case '0': output.WriteByte(0)
+		case 't': output.WriteByte('\t')
+		case 'n': output.WriteByte('\n')
+		case 'r': output.WriteByte('\r')
+		case '"': output.WriteByte('"')
+		case '\\': output.WriteByte('\\')
+		default: return "", i, false
+		}
+		i++
+		last = i + 1
+	}
+	output.WriteString(s[last:end])
+	return output.String(), end + 1, true
+}
+
+// Returns a copy of a part of the document if the document is not retained
+func (this *parseState) retain(s string) string {
+	if this.source != nil && this.retained == nil { return strings.Clone(s) }
+	return s
+}
+
+// Returns the index of the end of the line that contains pos
+func (this *parseState) lineEnd(pos int) int {
+	if pos >= len(this.text) { return len(this.text) }
+	end := strings.IndexByte(this.text[pos:len(this.text)], '\n')
+	if end < 0 { return len(this.text) }
+	return pos + end
+}
+
+// Returns true if s is a float as written in TOML: an optional sign, digits,
+// and a fraction with digits on both sides of the dot, an exponent or both, or
+// else inf or nan. The spelling is kept as is, so anything else would be
+// written back as an invalid float.
+func isFloat(s string) bool {
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') { s = s[1:len(s)] }
+	if s == "inf" || s == "nan" { return true }
+	digits := func(i int) int {
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' { i++ }
+		return i
+	}
+	i := digits(0)
+	if i == 0 { return false }
+	hasFraction := i < len(s) && s[i] == '.'
+	if hasFraction {
+		end := digits(i + 1)
+		if end == i + 1 { return false }
+		i = end
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') { i++ }
+		end := digits(i)
+		if end == i { return false }
+		return end == len(s)
+	}
+	return hasFraction && i == len(s)
+}
+
+func skipSpaces(s string, pos int) int {
+	for pos < len(s) && (s[pos] == ' ' || s[pos] == '\t' || s[pos] == '\r') {
+		pos++
+	}
+	return pos
+}
+
+// Returns s without its trailing spaces, tabs and carriage returns
+func trimSpacesRight(s string) string {
+	end := len(s)
+	for end > 0 && (s[end - 1] == ' ' || s[end - 1] == '\t' || s[end - 1] == '\r') { end-- }
+	return s[0:end]
+}
+
+// Returns true if the number parsed by parseNumber() has no fraction nor
+// exponent, and isn't inf or nan
+func isInteger(number string) bool {
+	for i := 0; i < len(number); i++ {
+		switch number[i] {
+		case '.', 'e', 'E', 'n': return false
+		}
+	}
+	return true
+}
+
+// Returns the header and its trailing comment, if the line is a section header
+func splitHeader(line string) (string, string, bool) {
+	if line[0] != '[' { return "", "", false }
+	header, comment := line, ""
+	if line[len(line) - 1] != ']' { header, comment = cleanRawValue(line) }
+	return header, comment, len(header) >= 2 && header[0] == '[' && header[len(header) - 1] == ']'
+}
+
+// Returns true if s starts with a plain key followed by "=", or with a section
+// header made of plain names. Inside a multi-line array, such a line means
+// that the array is unterminated.
+func isBoundary(s string) bool {
+	start := skipSpaces(s, 0)
+	if start < len(s) && s[start] == '[' {
+		end := strings.IndexByte(s, '\n')
+		if end < 0 { end = len(s) }
+		header, _, ok := splitHeader(strings.TrimRight(s[start:end], " \t\r"))
+		if ok && len(header) >= 4 && header[1] == '[' && header[len(header) - 2] == ']' { header = header[1:len(header) - 1] }
+		return ok && isBareName(header[1:len(header) - 1])
+	}
+
+	i := start
+	for i < len(s) {
+		c, size := utf8.DecodeRuneInString(s[i:len(s)])
+		if !isNameRune(c) { break }
+		i += size
+	}
+	if i == start { return false }
+	i = skipSpaces(s, i)
+	return i < len(s) && s[i] == '='
+}
+
+// Returns true for keys and dotted section names that only contain letters,
+// digits, "_" and "-"
+func isBareName(s string) bool {
+	if s == "" { return false }
+	for _, c := range s {
+		if !isNameRune(c) { return false }
+	}
+	return true
+}
+
+func isNameRune(c rune) bool {
+	return c == '.' || c == '_' || c == '-' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// Returns the change in array nesting caused by a line, ignoring the brackets
+// in strings. Comments must have been blanked.
+func bracketDepth(s string) int {
+	depth := 0
+	inString := false
+	escape := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if escape {
+			escape = false
+		} else if inString && c == '\\' {
+			escape = true
+		} else if c == '"' {
+			inString = !inString
+		} else if !inString && c == '[' {
+			depth++
+		} else if !inString && c == ']' {
+			depth--
+		}
+	}
+	return depth
+}
@@ -0,0 +1,504 @@
+package toml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSON patches (RFC 6902) and merge patches (RFC 7386) address the document as
+// if it was converted to JSON: sections and tables are objects, so the key path
+// servers.alpha.ip becomes the pointer /servers/alpha/ip, and array elements
+// are addressed by index, as in /database/ports/0 or /fruits/0/name for an
+// [[array of tables]]. Objects added in arrays or tables become tables.
+//
+// The JSON values are converted to the kind of the value they replace, if any:
+// a string replacing a date must be an RFC 3339 date, and a number replacing a
+// float is always a float. New strings that are RFC 3339 dates become dates.
+
+type patchOperation struct {
+	Op string `json:"op"`
+	Path *string `json:"path"`
+	From *string `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Returns a copy of the document with the JSON patch applied. If any of the
+// operations fails, including a "test" operation, none of them is applied.
+func (this Document) ApplyPatch(patch []byte) (Document, error) {
+	var operations []patchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil { return this, errors.New("toml: invalid JSON patch: " + err.Error()) }
+
+	builder := this.Builder()
+	builder.edit()
+	for i, operation := range operations {
+		if err := builder.applyOperation(operation); err != nil {
+			path := ""
+			if operation.Path != nil { path = *operation.Path }
+			return this, fmt.Errorf("toml: JSON patch operation %d (%s %s): %s", i, operation.Op, path, err)
+		}
+	}
+	return builder.Document(), nil
+}
+
+// Returns a copy of the document with the JSON merge patch applied
+func (this Document) ApplyMergePatch(patch []byte) (Document, error) {
+	raw, err := decodeJSON(patch)
+	if err != nil { return this, errors.New("toml: invalid JSON merge patch: " + err.Error()) }
+	object, ok := raw.(map[string]interface{})
+	if !ok { return this, errors.New("toml: JSON merge patch must be an object") }
+
+	builder := this.Builder()
+	builder.edit()
+	if err := mergePatch(builder.root, object); err != nil { return this, errors.New("toml: JSON merge patch: " + err.Error()) }
+	return builder.Document(), nil
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	var output interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&output)
+	return output, err
+}
+
+func splitPointer(pointer string) ([]string, error) {
+	if pointer == "" { return nil, nil }
+	if pointer[0] != '/' { return nil, errors.New("invalid JSON pointer: " + pointer) }
+	parts := strings.Split(pointer[1:len(pointer)], "/")
+	for i, part := range parts {
+		parts[i] = strings.Replace(strings.Replace(part, "~1", "/", -1), "~0", "~", -1)
+	}
+	return parts, nil
+}
+
+func (this *Builder) applyOperation(operation patchOperation) error {
+	if operation.Path == nil { return errors.New("missing path") }
+	path, err := splitPointer(*operation.Path)
+	if err != nil { return err }
+
+	var from []string
+	if operation.Op == "move" || operation.Op == "copy" {
+		if operation.From == nil { return errors.New("missing from") }
+		from, err = splitPointer(*operation.From)
+		if err != nil { return err }
+	}
+
+	var raw interface{}
+	if operation.Op == "add" || operation.Op == "replace" || operation.Op == "test" {
+		if operation.Value == nil { return errors.New("missing value") }
+		raw, err = decodeJSON(operation.Value)
+		if err != nil { return err }
+	}
+
+	switch operation.Op {
+	case "add", "replace":
+		current, _ := this.read(path)
+		item, err := fromJSON(raw, current)
+		if err != nil { return err }
+		if operation.Op == "replace" {
+			if current == nil { return errors.New("path not found") }
+			return this.write(path, item, true)
+		}
+		return this.write(path, item, false)
+
+	case "remove":
+		return this.remove(path)
+
+	case "move", "copy":
+		item, err := this.read(from)
+		if err != nil { return err }
+		if node, ok := item.(*Node); ok { item = node.Clone() }
+		if operation.Op == "move" {
+			if len(path) > len(from) && reflect.DeepEqual(path[0:len(from)], from) { return errors.New("can't move a value into itself") }
+			if err := this.remove(from); err != nil { return err }
+		}
+		return this.write(path, item, false)
+
+	case "test":
+		current, err := this.read(path)
+		if err != nil { return err }
+		if !jsonEqual(current, raw) { return errors.New("test failed") }
+		return nil
+	}
+
+	return errors.New("unknown operation")
+}
+
+// Walks the path down to the first value, and returns the last node found
+// along with the rest of the path.
+func (this *Builder) walk(path []string) (*Node, []string) {
+	node := this.root
+	for i, name := range path {
+		if node.kind == kindValue { return node, path[i:len(path)] }
+		child, ok := node.Child(name)
+		if !ok { return node, path[i:len(path)] }
+		node = child
+	}
+	return node, nil
+}
+
+// Returns the section (as a *Node) or the value at the given path
+func (this *Builder) read(path []string) (interface{}, error) {
+	node, rest := this.walk(path)
+	if len(rest) == 0 {
+		if node.kind == kindValue { return node.value, nil }
+		return node, nil
+	}
+	if node.kind != kindValue { return nil, errors.New("path not found") }
+
+	v := node.value
+	for _, part := range rest {
+		index, err := elementIndex(v, part)
+		if err != nil { return nil, err }
+		v = v.array[index]
+	}
+	return v, nil
+}
+
+func (this *Builder) remove(path []string) error {
+	if len(path) == 0 { return errors.New("can't remove the document") }
+	node, rest := this.walk(path)
+	if len(rest) == 0 {
+		node.parent.removeChild(node.name)
+		return nil
+	}
+	if node.kind != kindValue { return errors.New("path not found") }
+
+	v, err := updateValue(node.value, rest, func(v Value, part string) (Value, error) {
+		index, err := elementIndex(v, part)
+		if err != nil { return v, err }
+		start := index
+		if v.kind == kindTable { start-- } // The name of the key
+		v.array = append(v.array[0:start:start], v.array[index + 1:len(v.array)]...)
+		return v, nil
+	})
+	if err != nil { return err }
+	node.value = v
+	return nil
+}
+
+// Adds the item (a value or a section) at the given path. If replace is true,
+// array elements are replaced instead of inserted.
+func (this *Builder) write(path []string, item interface{}, replace bool) error {
+	if len(path) == 0 {
+		section, ok := item.(*Node)
+		if !ok { return errors.New("the document can only be replaced by an object") }
+		this.root.children = nil
+		this.root.order = nil
+		for _, child := range section.Children() {
+			this.root.setChild(child.name, child)
+		}
+		return nil
+	}
+
+	node, rest := this.walk(path)
+	if len(rest) == 0 {
+		if v, ok := item.(Value); ok && node.kind == kindValue {
+			node.value = keepSensitive(node.value, v)
+			return nil
+		}
+		return attach(node.parent, node.name, item)
+	}
+
+	if node.kind != kindValue {
+		if len(rest) > 1 { return errors.New("path not found") }
+		return attach(node, rest[0], item)
+	}
+
+	newValue, ok := item.(Value)
+	if !ok { newValue = sectionValue(item.(*Node)) }
+	v, err := updateValue(node.value, rest, func(v Value, part string) (Value, error) {
+		array := make([]Value, len(v.array), len(v.array) + 2)
+		copy(array, v.array)
+		newValue := keepSensitive(v, newValue)
+		if v.kind == kindTable {
+			if err := checkPatchKey(part); err != nil { return v, err }
+			if index, err := elementIndex(v, part); err == nil {
+				array[index] = keepSensitive(array[index], newValue)
+			} else {
+				array = append(array, NewString(part), newValue)
+			}
+			v.array = array
+			return v, nil
+		}
+
+		index, err := arrayIndex(part, len(array), !replace)
+		if err != nil { return v, err }
+		if replace {
+			array[index] = keepSensitive(array[index], newValue)
+		} else {
+			array = append(array, newValue)
+			copy(array[index + 1:len(array)], array[index:len(array) - 1])
+			array[index] = newValue
+		}
+		v.array = array
+		return v, nil
+	})
+	if err != nil { return err }
+	node.value = v
+	return nil
+}
+
+func attach(section *Node, name string, item interface{}) error {
+	if err := checkPatchKey(name); err != nil { return err }
+	node, ok := item.(*Node)
+	if !ok {
+		node = newNodePointer()
+		node.kind = kindValue
+		node.value = item.(Value)
+	}
+	node.name = name
+	section.setChild(name, node)
+	return nil
+}
+
+func checkPatchKey(name string) error {
+	if name == "" || strings.Contains(name, ".") { return errors.New("invalid key: " + strconv.Quote(name)) }
+	return nil
+}
+
+// Calls fn with the array or table designated by all but the last part of the
+// path, and returns a copy of v where it's replaced by the result. fn must not
+// modify the elements of the array or table it's given.
+func updateValue(v Value, path []string, fn func(v Value, part string) (Value, error)) (Value, error) {
+	if v.kind != kindArray && v.kind != kindTable { return v, errors.New("path not found") }
+	if len(path) == 1 { return fn(v, path[0]) }
+
+	index, err := elementIndex(v, path[0])
+	if err != nil { return v, err }
+	element, err := updateValue(v.array[index], path[1:len(path)], fn)
+	if err != nil { return v, err }
+	array := make([]Value, len(v.array))
+	copy(array, v.array)
+	array[index] = element
+	v.array = array
+	return v, nil
+}
+
+// Returns the index in Value.array of an array element, or of the value of a
+// table key
+func elementIndex(v Value, part string) (int, error) {
+	if v.kind == kindArray { return arrayIndex(part, len(v.array), false) }
+	if v.kind != kindTable { return 0, errors.New("path not found") }
+	for i := 0; i + 1 < len(v.array); i += 2 {
+		if v.array[i].text == part { return i + 1, nil }
+	}
+	return 0, errors.New("path not found")
+}
+
+func arrayIndex(part string, length int, allowEnd bool) (int, error) {
+	if part == "-" && allowEnd { return length, nil }
+	index, err := strconv.Atoi(part)
+	if err != nil || index < 0 || (len(part) > 1 && part[0] == '0') { return 0, errors.New("invalid array index: " + part) }
+	if index > length || (index == length && !allowEnd) { return 0, errors.New("array index out of range: " + part) }
+	return index, nil
+}
+
+// Converts a JSON value to a section (as a *Node) or a value. The current item
+// at the same location, if any, is used to keep the TOML kinds.
+func fromJSON(raw interface{}, current interface{}) (interface{}, error) {
+	object, ok := raw.(map[string]interface{})
+	if hint, isValue := current.(Value); !ok || (isValue && hint.kind == kindTable) {
+		return valueFromJSON(raw, hint)
+	}
+
+	section := newNodePointer()
+	section.kind = kindSection
+	hint, _ := current.(*Node)
+	for name, rawChild := range object {
+		var currentChild interface{}
+		if hint != nil {
+			if child, ok := hint.Child(name); ok {
+				currentChild = child
+				if child.kind == kindValue { currentChild = child.value }
+			}
+		}
+		item, err := fromJSON(rawChild, currentChild)
+		if err != nil { return nil, err }
+		if err := attach(section, name, item); err != nil { return nil, err }
+	}
+	return section, nil
+}
+
+func valueFromJSON(raw interface{}, hint Value) (Value, error) {
+	switch raw := raw.(type) {
+	case bool:
+		return NewBool(raw), nil
+
+	case json.Number:
+		s := string(raw)
+		if hint.kind == kindFloat || strings.ContainsAny(s, ".eE") {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil { return Value{}, errors.New("invalid number: " + s) }
+			return NewFloat(f), nil
+		}
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil && hint.kind == kindBigInt {
+			if bigInt, ok := new(big.Int).SetString(s, 10); ok { return NewBigInt(bigInt), nil }
+		}
+		if err != nil { return Value{}, errors.New("invalid integer: " + s) }
+		return NewInt(i), nil
+
+	case string:
+		if hint.kind == kindString { return NewString(raw), nil }
+		if date, index, ok := parseDateTime(raw); isDateTime(raw) && ok && index == len(raw) { return date, nil }
+		if isDateKind(hint.kind) { return Value{}, errors.New("invalid date: " + raw) }
+		return NewString(raw), nil
+
+	case []interface{}:
+		array := make([]Value, len(raw))
+		for i, rawElement := range raw {
+			var elementHint Value
+			if i < len(hint.array) { elementHint = hint.array[i] }
+			element, err := valueFromJSON(rawElement, elementHint)
+			if err != nil { return Value{}, err }
+			array[i] = element
+		}
+		return NewArray(array...), nil
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(raw))
+		for key := range raw {
+			if err := checkPatchKey(key); err != nil { return Value{}, err }
+			keys = append(keys, key)
+		}
+		sortKeys(keys, hint)
+		values := make([]Value, len(keys))
+		for i, key := range keys {
+			elementHint, _ := hint.Get(key)
+			element, err := valueFromJSON(raw[key], elementHint)
+			if err != nil { return Value{}, err }
+			values[i] = element
+		}
+		return NewTable(keys, values), nil
+	}
+
+	return Value{}, errors.New("null is not a valid value")
+}
+
+// Sorts the keys of a JSON object in the order of the table they replace, with
+// the new keys at the end in alphabetical order
+func sortKeys(keys []string, hint Value) {
+	order := make(map[string]int)
+	for i, key := range hint.Keys() {
+		order[key] = i
+	}
+	sort.Slice(keys, func(i int, j int) bool {
+		a, aExists := order[keys[i]]
+		b, bExists := order[keys[j]]
+		if aExists && bExists { return a < b }
+		if aExists != bExists { return aExists }
+		return keys[i] < keys[j]
+	})
+}
+
+func mergePatch(section *Node, patch map[string]interface{}) error {
+	for name, raw := range patch {
+		child, exists := section.Child(name)
+		if raw == nil {
+			if exists { section.removeChild(name) }
+			continue
+		}
+
+		if object, ok := raw.(map[string]interface{}); ok && exists && child.kind == kindValue && child.value.kind == kindTable {
+			v, err := mergeTable(child.value, object)
+			if err != nil { return fmt.Errorf("%s: %s", name, err) }
+			child.value = keepSensitive(child.value, v)
+			continue
+		}
+
+		if object, ok := raw.(map[string]interface{}); ok {
+			if !exists || child.kind != kindSection {
+				child = newNodePointer()
+				child.kind = kindSection
+				if err := attach(section, name, child); err != nil { return err }
+			}
+			if err := mergePatch(child, object); err != nil { return err }
+			continue
+		}
+
+		var hint Value
+		if exists && child.kind == kindValue { hint = child.value }
+		v, err := valueFromJSON(raw, hint)
+		if err != nil { return fmt.Errorf("%s: %s", name, err) }
+		if exists && child.kind == kindValue {
+			child.value = keepSensitive(child.value, v)
+		} else if err := attach(section, name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns a copy of the table with the merge patch applied
+func mergeTable(table Value, patch map[string]interface{}) (Value, error) {
+	names := make([]string, 0, len(patch))
+	for name := range patch {
+		names = append(names, name)
+	}
+	sortKeys(names, table)
+
+	for _, name := range names {
+		raw := patch[name]
+		index, err := elementIndex(table, name)
+		exists := err == nil
+		array := make([]Value, len(table.array), len(table.array) + 2)
+		copy(array, table.array)
+
+		if raw == nil {
+			if exists { array = append(array[0:index - 1], array[index + 1:len(array)]...) }
+			table.array = array
+			continue
+		}
+
+		var v Value
+		if object, ok := raw.(map[string]interface{}); ok && exists && table.array[index].kind == kindTable {
+			v, err = mergeTable(table.array[index], object)
+		} else {
+			var hint Value
+			if exists { hint = table.array[index] }
+			v, err = valueFromJSON(raw, hint)
+		}
+		if err != nil { return table, fmt.Errorf("%s: %s", name, err) }
+
+		v = keepSensitive(table, v)
+		if exists {
+			array[index] = keepSensitive(array[index], v)
+		} else if err := checkPatchKey(name); err != nil {
+			return table, err
+		} else {
+			array = append(array, NewString(name), v)
+		}
+		table.array = array
+	}
+	return table, nil
+}
+
+// Compares a section or value with a JSON value. Numbers are equal if they
+// have the same value, whatever their kind, and sensitive values are compared
+// with their actual value.
+func jsonEqual(item interface{}, raw interface{}) bool {
+	var itemJSON []byte
+	var err error
+	if node, ok := item.(*Node); ok {
+		node = node.Clone()
+		node.eachValue(func(node *Node) { node.value = node.value.Reveal() })
+		itemJSON, err = json.Marshal(node)
+	} else {
+		itemJSON, err = json.Marshal(item.(Value).Reveal())
+	}
+	if err != nil { return false }
+	rawJSON, err := json.Marshal(raw)
+	if err != nil { return false }
+
+	var a, b interface{}
+	if json.Unmarshal(itemJSON, &a) != nil || json.Unmarshal(rawJSON, &b) != nil { return false }
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,107 @@
+package toml
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+type Position struct {
+	File string // Empty if the document wasn't parsed from a file
+	Line int // Starts at 1
+	Column int // In characters, starts at 1
+	Offset int // Byte offset of the start
+	End int // Byte offset just after the end
+}
+
+// Returns false for the nodes and values that were not parsed from a document
+func (this Position) IsValid() bool {
+	return this.Line > 0
+}
+
+// Returns the position as file:line:column
+func (this Position) String() string {
+	if !this.IsValid() { return "-" }
+	output := strconv.Itoa(this.Line) + ":" + strconv.Itoa(this.Column)
+	if this.File != "" { output = this.File + ":" + output }
+	return output
+}
+
+type sourceFile struct {
+	name string
+	text string
+	once sync.Once
+	lineStarts []int // Only built when a position is needed
+}
+
+func newSourceFile(name string, text string) *sourceFile {
+	return &sourceFile{name: name, text: text}
+}
+
+func (this *sourceFile) lines() []int {
+	this.once.Do(func() {
+		this.lineStarts = []int{0}
+		for i := 0; i < len(this.text); i++ {
+			if this.text[i] == '\n' { this.lineStarts = append(this.lineStarts, i + 1) }
+		}
+	})
+	return this.lineStarts
+}
+
+func (this *sourceFile) position(offset int, end int) Position {
+	if this == nil { return Position{} }
+	this.lines()
+	line := sort.Search(len(this.lineStarts), func(i int) bool { return this.lineStarts[i] > offset })
+	column := utf8.RuneCountInString(this.text[this.lineStarts[line - 1]:offset]) + 1
+	return Position{this.name, line, column, offset, end}
+}
+
+// Returns the position of the key, or of the section header. For sections
+// that have no header of their own, such as "a" in [a.b], this is the position
+// of the first header that contains them.
+func (this *Node) Position() Position {
+	return this.source.position(this.offset, this.end)
+}
+
+// Returns the position of the value in the document it was parsed from
+func (this Value) Position() Position {
+	return this.source.position(int(this.offset), int(this.end))
+}
+
+// Replaces the comments with spaces, so that the offsets don't change
+func blankComments(s string) string {
+	output := []byte(s)
+	inString := false
+	inComment := false
+	escape := false
+	for i := 0; i < len(output); i++ {
+		c := output[i]
+		if c == '\n' {
+			inString = false
+			inComment = false
+			continue
+		}
+		if inComment {
+			output[i] = ' '
+			continue
+		}
+		if escape {
+			escape = false
+			continue
+		}
+		if c == '"' {
+			inString = !inString
+			continue
+		}
+		if inString && c == '\\' {
+			escape = true
+			continue
+		}
+		if c == '#' && !inString {
+			inComment = true
+			output[i] = ' '
+		}
+	}
+	return string(output)
+}
@@ -0,0 +1,350 @@
+package toml
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A compiled query, which selects values and sections from a document. A query
+// is made of dot-separated steps:
+//
+//	name        The section or key with this name. Use quotes if the name
+//	            contains a dot or a bracket: servers."alpha.beta".ip
+//	*           Every section or key
+//	**          This node and every section, key and array element below it,
+//	            at any depth
+//	[2]         The element at this index of an array (negative indexes start
+//	            from the end)
+//	[*]         Every element of an array, or every section or key
+//	[?expr]     The sections, or array elements, for which expr is true.
+//	            expr compares a key (or @ for the array element itself) with
+//	            a TOML value using ==, !=, <, <=, > or >=
+//
+// Inline tables and the tables of an [[array of tables]] are queried like
+// sections. For example: servers.*.ip, **.port, servers[?enabled==true].name,
+// fruits[0].name or clients.data[0][1]
+type Query struct {
+	source string
+	steps []queryStep
+}
+
+type Match struct {
+	Path string // eg. servers.alpha.ip, clients.data[0][1] or fruits[0].name
+	Node *Node // The section or key, nil for array elements and for the keys of tables
+	Value Value // Not set for sections
+	key *Node // The key whose value contains this one, for array elements and the keys of tables
+	location []string // Indexes and table keys, from the value of key down to this value
+}
+
+const (
+	stepName = 1
+	stepWildcard = 2
+	stepDescendants = 3
+	stepIndex = 4
+	stepAll = 5
+	stepFilter = 6
+)
+
+type queryStep struct {
+	kind int
+	name string
+	index int
+	filterKey string
+	filterOperator string
+	filterValue Value
+}
+
+func CompileQuery(query string) (*Query, error) {
+	output := &Query{source: query}
+	s := query
+	for {
+		if len(s) > 0 && s[0] != '[' {
+			step, rest, err := parseNameStep(s)
+			if err != nil { return nil, fmt.Errorf("toml: invalid query %q: %s", query, err) }
+			output.steps = append(output.steps, step)
+			s = rest
+		}
+
+		for len(s) > 0 && s[0] == '[' {
+			step, rest, err := parseBracketStep(s)
+			if err != nil { return nil, fmt.Errorf("toml: invalid query %q: %s", query, err) }
+			output.steps = append(output.steps, step)
+			s = rest
+		}
+
+		if len(s) == 0 { break }
+		if s[0] != '.' || len(s) == 1 { return nil, fmt.Errorf("toml: invalid query %q: unexpected %q", query, s) }
+		s = s[1:len(s)]
+	}
+
+	if len(output.steps) == 0 { return nil, fmt.Errorf("toml: invalid query %q: empty query", query) }
+	return output, nil
+}
+
+// Same as CompileQuery() but panics if the query is invalid
+func MustCompileQuery(query string) *Query {
+	output, err := CompileQuery(query)
+	if err != nil { panic(err.Error()) }
+	return output
+}
+
+func parseNameStep(s string) (queryStep, string, error) {
+	var step queryStep
+	if s[0] == '"' {
+		name, index, ok := parseString(s)
+		if !ok || index == 0 { return step, s, errors.New("invalid quoted name") }
+		step.kind = stepName
+		step.name = name
+		return step, s[index:len(s)], nil
+	}
+
+	end := strings.IndexAny(s, ".[")
+	if end < 0 { end = len(s) }
+	name := strings.Trim(s[0:end], " \t")
+	if name == "" { return step, s, errors.New("empty name") }
+
+	if name == "**" {
+		step.kind = stepDescendants
+	} else if name == "*" {
+		step.kind = stepWildcard
+	} else {
+		step.kind = stepName
+		step.name = name
+	}
+	return step, s[end:len(s)], nil
+}
+
+func parseBracketStep(s string) (queryStep, string, error) {
+	var step queryStep
+	s = strings.TrimLeft(s[1:len(s)], " \t")
+
+	if len(s) > 0 && s[0] == '?' {
+		s = strings.TrimLeft(s[1:len(s)], " \t")
+		end := strings.IndexAny(s, "=!<>")
+		if end <= 0 { return step, s, errors.New("invalid filter") }
+		step.kind = stepFilter
+		step.filterKey = strings.Trim(s[0:end], " \t")
+		s = s[end:len(s)]
+		for _, operator := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+			if strings.HasPrefix(s, operator) {
+				step.filterOperator = operator
+				break
+			}
+		}
+		if step.filterOperator == "" { return step, s, errors.New("invalid filter operator") }
+		s = strings.TrimLeft(s[len(step.filterOperator):len(s)], " \t")
+		v, index, ok := parseValue(s)
+		if !ok || index == 0 { return step, s, errors.New("invalid filter value") }
+		step.filterValue = v
+		s = s[index:len(s)]
+	} else {
+		end := strings.Index(s, "]")
+		if end < 0 { return step, s, errors.New("missing ]") }
+		content := strings.Trim(s[0:end], " \t")
+		if content == "*" {
+			step.kind = stepAll
+		} else {
+			index, err := strconv.Atoi(content)
+			if err != nil { return step, s, errors.New("invalid index: " + content) }
+			step.kind = stepIndex
+			step.index = index
+		}
+		s = s[end:len(s)]
+	}
+
+	s = strings.TrimLeft(s, " \t")
+	if len(s) == 0 || s[0] != ']' { return step, s, errors.New("missing ]") }
+	return step, s[1:len(s)], nil
+}
+
+func (this *Query) String() string {
+	return this.source
+}
+
+// Returns the values and sections matched by the query, in declaration order
+func (this *Query) Find(doc Document) []Match {
+	matches := []Match{Match{Node: doc.root}}
+	for _, step := range this.steps {
+		var next []Match
+		for _, match := range matches {
+			next = step.apply(match, next)
+		}
+		matches = next
+	}
+	return matches
+}
+
+// Compiles the query and returns the values and sections it matches
+func (this Document) Query(query string) ([]Match, error) {
+	q, err := CompileQuery(query)
+	if err != nil { return nil, err }
+	return q.Find(this), nil
+}
+
+func nodeMatch(node *Node) Match {
+	output := Match{Path: node.FullName(), Node: node}
+	if node.kind == kindValue { output.Value = node.value }
+	return output
+}
+
+func (this Match) isSection() bool {
+	return this.Node != nil && this.Node.kind != kindValue
+}
+
+// Returns true for sections and tables, which have keys
+func (this Match) isTable() bool {
+	return this.isSection() || this.Value.kind == kindTable
+}
+
+// Returns the match of an element or key of this value
+func (this Match) inner(path string, component string, v Value) Match {
+	key := this.key
+	if key == nil { key = this.Node }
+	location := append(this.location[0:len(this.location):len(this.location)], component)
+	return Match{Path: path, Value: v, key: key, location: location}
+}
+
+func (this Match) elements() []Match {
+	if this.isSection() || this.Value.kind != kindArray { return nil }
+	output := make([]Match, len(this.Value.array))
+	for i, v := range this.Value.array {
+		output[i] = this.inner(this.Path + "[" + strconv.Itoa(i) + "]", strconv.Itoa(i), v)
+	}
+	return output
+}
+
+func (this Match) children() []Match {
+	var output []Match
+	if this.isSection() {
+		for _, node := range this.Node.Children() {
+			output = append(output, nodeMatch(node))
+		}
+	} else if this.Value.kind == kindTable {
+		for i := 0; i + 1 < len(this.Value.array); i += 2 {
+			name := this.Value.array[i].text
+			output = append(output, this.inner(this.Path + "." + name, name, this.Value.array[i + 1]))
+		}
+	}
+	return output
+}
+
+func (this Match) child(name string) (Match, bool) {
+	if this.isSection() {
+		node, ok := this.Node.Child(name)
+		if !ok { return Match{}, false }
+		return nodeMatch(node), true
+	}
+	v, ok := this.Value.Get(name)
+	if !ok { return Match{}, false }
+	return this.inner(this.Path + "." + name, name, v), true
+}
+
+// Returns the value at the given key path, below a section or table
+func (this Match) get(path string) (Value, bool) {
+	if this.isSection() { return this.Node.GetValue(path) }
+	v := this.Value
+	for _, name := range strings.Split(path, ".") {
+		var ok bool
+		v, ok = v.Get(name)
+		if !ok { return Value{}, false }
+	}
+	return v, true
+}
+
+func (this Match) descendants(output []Match) []Match {
+	output = append(output, this)
+	for _, child := range this.children() {
+		output = child.descendants(output)
+	}
+	for _, element := range this.elements() {
+		output = element.descendants(output)
+	}
+	return output
+}
+
+func (this queryStep) apply(match Match, output []Match) []Match {
+	switch this.kind {
+	case stepName:
+		if !match.isTable() { return output }
+		if child, ok := match.child(this.name); ok { output = append(output, child) }
+
+	case stepWildcard:
+		output = append(output, match.children()...)
+
+	case stepDescendants:
+		output = match.descendants(output)
+
+	case stepIndex:
+		elements := match.elements()
+		index := this.index
+		if index < 0 { index += len(elements) }
+		if index >= 0 && index < len(elements) { output = append(output, elements[index]) }
+
+	case stepAll:
+		output = append(output, match.children()...)
+		output = append(output, match.elements()...)
+
+	case stepFilter:
+		for _, candidate := range append(match.children(), match.elements()...) {
+			if this.matches(candidate) { output = append(output, candidate) }
+		}
+	}
+	return output
+}
+
+func (this queryStep) matches(candidate Match) bool {
+	var v Value
+	if this.filterKey == "@" {
+		if candidate.isSection() { return false }
+		v = candidate.Value
+	} else {
+		if !candidate.isTable() { return false }
+		var ok bool
+		v, ok = candidate.get(this.filterKey)
+		if !ok { return false }
+	}
+
+	comparison, ok := compareValues(v, this.filterValue)
+	if !ok { return this.filterOperator == "!=" }
+	switch this.filterOperator {
+	case "==": return comparison == 0
+	case "!=": return comparison != 0
+	case "<": return comparison < 0
+	case "<=": return comparison <= 0
+	case ">": return comparison > 0
+	case ">=": return comparison >= 0
+	}
+	return false
+}
+
+// Returns -1, 0 or 1, or false if the values can't be compared
+func compareValues(a Value, b Value) (int, bool) {
+	if (a.kind == kindInt || a.kind == kindBigInt) && (b.kind == kindInt || b.kind == kindBigInt) && a.kind != b.kind { return a.AsBigInt().Cmp(b.AsBigInt()), true }
+	if (a.kind == kindInt || a.kind == kindFloat) && (b.kind == kindInt || b.kind == kindFloat) {
+		if a.kind == kindInt && b.kind == kindInt { return compareOrdered(a.AsInt64() < b.AsInt64(), a.AsInt64() > b.AsInt64()), true }
+		aFloat, bFloat := a.AsFloat64(), b.AsFloat64()
+		if a.kind == kindInt { aFloat = float64(a.AsInt64()) }
+		if b.kind == kindInt { bFloat = float64(b.AsInt64()) }
+		return compareOrdered(aFloat < bFloat, aFloat > bFloat), true
+	}
+	if a.kind != b.kind { return 0, false }
+	switch a.kind {
+	case kindString: return strings.Compare(a.text, b.text), true
+	case kindBigInt: return a.AsBigInt().Cmp(b.AsBigInt()), true
+	case kindDate: return compareOrdered(a.AsDate().Before(b.AsDate()), a.AsDate().After(b.AsDate())), true
+	case kindLocalDate, kindLocalTime, kindLocalDateTime:
+		aTime, bTime := int64(a.scalar), int64(b.scalar)
+		return compareOrdered(aTime < bTime || (aTime == bTime && a.nsec < b.nsec), aTime > bTime || (aTime == bTime && a.nsec > b.nsec)), true
+	case kindBool, kindArray:
+		if a.equal(b) { return 0, true }
+	}
+	return 0, false
+}
+
+func compareOrdered(less bool, greater bool) int {
+	if less { return -1 }
+	if greater { return 1 }
+	return 0
+}
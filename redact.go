@@ -0,0 +1,143 @@
+package toml
+
+import (
+	"reflect"
+	"strings"
+)
+
+const (
+	precisionMask = 0x0f
+	flagSensitive = 0x80
+)
+
+// Written instead of the sensitive values
+const redacted = "[redacted]"
+
+func (this Value) precision() int {
+	return int(this.flags & precisionMask)
+}
+
+func clampPrecision(precision int) int {
+	if precision < 0 { return 0 }
+	if precision > 9 { return 9 }
+	return precision
+}
+
+// Marks the value as sensitive, such as a password, along with the elements of
+// an array or a table. Sensitive values are written as "[redacted]" by String(),
+// Raw(), in JSON, diffs and error messages. The accessors, such as AsString(),
+// and Decode() still return the actual value.
+func Sensitive(v Value) Value {
+	v.flags |= flagSensitive
+	if v.array != nil {
+		array := make([]Value, len(v.array))
+		for i, element := range v.array {
+			array[i] = Sensitive(element)
+		}
+		v.array = array
+	}
+	return v
+}
+
+// Returns the value that replaces old, which is sensitive if old was
+func keepSensitive(old Value, value Value) Value {
+	if old.flags & flagSensitive != 0 { return Sensitive(value) }
+	return value
+}
+
+// Returns s, or "[redacted]" if the value is sensitive, for error messages
+func (this Value) redact(s string) string {
+	if this.flags & flagSensitive != 0 { return redacted }
+	return s
+}
+
+// Returns true if the value, or one of its elements, is sensitive
+func (this Value) IsSensitive() bool {
+	if this.flags & flagSensitive != 0 { return true }
+	for _, element := range this.array {
+		if element.IsSensitive() { return true }
+	}
+	return false
+}
+
+// Returns a copy of the value that is not sensitive, nor any of its elements,
+// to write it on purpose
+func (this Value) Reveal() Value {
+	if !this.IsSensitive() { return this }
+	this.flags &^= flagSensitive
+	if this.array != nil {
+		array := make([]Value, len(this.array))
+		for i, element := range this.array {
+			array[i] = element.Reveal()
+		}
+		this.array = array
+	}
+	return this
+}
+
+// Returns a copy of the document in which the values matched by the patterns
+// are sensitive, see Sensitive(). The patterns are queries, such as
+// "*.password" for the password keys of the top-level sections or "**.token"
+// for the token keys at any depth, including the keys of inline tables and
+// arrays of tables, and all the values of the sections they match are
+// sensitive. Values that are replaced afterwards, by a Builder, ApplyEnv() or
+// a patch, stay sensitive.
+func (this Document) MarkSensitive(patterns ...string) (Document, error) {
+	builder := this.Builder()
+	builder.edit()
+	for _, pattern := range patterns {
+		matches, err := this.Query(pattern)
+		if err != nil { return this, err }
+		for _, match := range matches {
+			if match.isSection() {
+				match.Node.eachValue(func(node *Node) { builder.markSensitive(node.path(), nil) })
+			} else if match.key != nil {
+				builder.markSensitive(match.key.path(), match.location)
+			} else {
+				builder.markSensitive(match.Node.path(), nil)
+			}
+		}
+	}
+	return builder.Document(), nil
+}
+
+// Marks the value of a key as sensitive, or the element or table key at the
+// given location in its value
+func (this *Builder) markSensitive(names []string, location []string) {
+	node, ok := this.find(names)
+	if !ok { return }
+	if len(location) == 0 {
+		node.value = Sensitive(node.value)
+		return
+	}
+	v, err := updateValue(node.value, location, func(v Value, part string) (Value, error) {
+		index, err := elementIndex(v, part)
+		if err != nil { return v, err }
+		array := make([]Value, len(v.array))
+		copy(array, v.array)
+		array[index] = Sensitive(array[index])
+		v.array = array
+		return v, nil
+	})
+	if err == nil { node.value = v }
+}
+
+// Returns a copy of the document without sensitive values, to write it on
+// purpose, eg. doc.Reveal().String() to save it to a file
+func (this Document) Reveal() Document {
+	if this.root == nil { return this }
+	builder := this.Builder()
+	builder.edit()
+	builder.root.eachValue(func(node *Node) { node.value = node.value.Reveal() })
+	return builder.Document()
+}
+
+// Returns true if the field has the sensitive option, eg.
+// `toml:"password,sensitive"`
+func isSensitiveField(field reflect.StructField) bool {
+	options := strings.Split(field.Tag.Get("toml"), ",")
+	for _, option := range options[1:len(options)] {
+		if option == "sensitive" { return true }
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+package toml
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const schemaVersion = "http://json-schema.org/draft-07/schema#"
+
+// Generates a JSON Schema of the documents that decode into the struct that v
+// is or points to, for the editors that complete and validate TOML files. The
+// fields are named like in Decode(), and keys that match no field are
+// invalid, like with Strict. The `default` tag gives the default of a key, and
+// the `validate` tag its constraints, eg. `validate:"required,min=1,max=10"`:
+//
+//	required        the key must be defined
+//	min=n, max=n    bounds of a number, or of the length of a string, an array or a map
+//	len=n           length of a string, an array or a map
+//	oneof=a b c     allowed values, separated by spaces
+//
+// The descriptions are taken from comments, keyed like the ones returned by
+// ParseComments(), which may be nil. Recursive types, such as a tree whose
+// nodes have children of the same type, are referenced with "$ref".
+func StructSchema(v interface{}, comments map[string]string) ([]byte, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr { t = t.Elem() }
+	if t == nil || t.Kind() != reflect.Struct || isDateType(t) { return nil, fmt.Errorf("toml: StructSchema needs a struct") }
+
+	generator := &schemaGenerator{
+		root: t,
+		comments: comments,
+		visiting: make(map[reflect.Type]bool),
+		recursive: make(map[reflect.Type]bool),
+		definitions: make(map[string]interface{}),
+	}
+	schema, err := generator.typeSchema(t, t.Name())
+	if err != nil { return nil, err }
+	schema["$schema"] = schemaVersion
+	if t.Name() != "" { schema["title"] = t.Name() }
+	if len(generator.definitions) > 0 { schema["definitions"] = generator.definitions }
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+type schemaGenerator struct {
+	root reflect.Type
+	comments map[string]string
+	visiting map[reflect.Type]bool // The structs whose schema is being generated
+	recursive map[reflect.Type]bool // The structs that contain themselves
+	definitions map[string]interface{} // The schemas of the recursive structs, except the root
+}
+
+// Returns a reference to the schema of a recursive struct
+func (this *schemaGenerator) ref(t reflect.Type) map[string]interface{} {
+	if t == this.root { return map[string]interface{}{"$ref": "#"} }
+	return map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+}
+
+func (this *schemaGenerator) typeSchema(t reflect.Type, name string) (map[string]interface{}, error) {
+	comments := this.comments
+	for t.Kind() == reflect.Ptr { t = t.Elem() }
+	if t.Name() != "" && t.Kind() == reflect.Struct { name = t.Name() }
+
+	switch t {
+	case timeType: return map[string]interface{}{"type": "string", "format": "date-time"}, nil
+	case localDateType: return map[string]interface{}{"type": "string", "format": "date"}, nil
+	case localTimeType: return map[string]interface{}{"type": "string", "format": "partial-time"}, nil
+	case localDateTimeType: return map[string]interface{}{"type": "string", "format": "partial-date-time"}, nil
+	case durationType, byteSizeType: return map[string]interface{}{"type": []string{"string", "integer"}}, nil
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) { return map[string]interface{}{}, nil }
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) { return map[string]interface{}{"type": "string"}, nil }
+
+	switch t.Kind() {
+	case reflect.Bool: return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.String: return map[string]interface{}{"type": "string"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64: return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr: return map[string]interface{}{"type": "integer", "minimum": 0}, nil
+	case reflect.Float32, reflect.Float64: return map[string]interface{}{"type": "number"}, nil
+	case reflect.Interface: return map[string]interface{}{}, nil
+
+	case reflect.Slice, reflect.Array:
+		items, err := this.typeSchema(t.Elem(), name)
+		if err != nil { return nil, err }
+		output := map[string]interface{}{"type": "array", "items": items}
+		if t.Kind() == reflect.Array { output["maxItems"] = t.Len() }
+		return output, nil
+
+	case reflect.Map:
+		values, err := this.typeSchema(t.Elem(), name)
+		if err != nil { return nil, err }
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+
+	case reflect.Struct:
+		if this.visiting[t] {
+			this.recursive[t] = true
+			return this.ref(t), nil
+		}
+		this.visiting[t] = true
+		defer delete(this.visiting, t)
+
+		properties := make(map[string]interface{})
+		var required []string
+		for _, field := range structFields(t) {
+			commentName := fieldCommentName(t, name, field.index)
+			schema, err := this.typeSchema(field.field.Type, commentName)
+			if err != nil { return nil, err }
+			if description := comments[commentName]; description != "" {
+				schema["description"] = description
+			} else if fieldType := indirectType(field.field.Type); fieldType.Name() != "" && comments[fieldType.Name()] != "" {
+				schema["description"] = comments[fieldType.Name()]
+			}
+
+			if text, ok := field.field.Tag.Lookup("default"); ok {
+				kind := Kind(0)
+				if indirectType(field.field.Type).Kind() == reflect.String { kind = kindString }
+				value, err := parseValueAs(text, kind)
+				if err != nil { return nil, fmt.Errorf("toml: %s: invalid default: %v", field.key, err) }
+				schema["default"] = value.jsonValue()
+			}
+			isRequired, err := addConstraints(schema, field.field.Tag.Get("validate"))
+			if err != nil { return nil, fmt.Errorf("toml: %s: %v", field.key, err) }
+			if isRequired { required = append(required, field.key) }
+			properties[field.key] = schema
+		}
+		output := map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+		if len(required) > 0 { output["required"] = required }
+		if this.recursive[t] && t != this.root {
+			this.definitions[t.Name()] = output
+			return this.ref(t), nil
+		}
+		return output, nil
+	}
+	return nil, fmt.Errorf("toml: cannot generate a schema for %s", t)
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr { t = t.Elem() }
+	return t
+}
+
+// Returns the name of a field in the comments, "Type.Field", or
+// "Type.Field.Field" for the fields of anonymous structs
+func fieldCommentName(t reflect.Type, name string, index []int) string {
+	for _, i := range index[0:len(index) - 1] {
+		field := t.Field(i)
+		t = indirectType(field.Type)
+		if t.Name() != "" {
+			name = t.Name()
+		} else {
+			name += "." + field.Name
+		}
+	}
+	return name + "." + t.Field(index[len(index) - 1]).Name
+}
+
+// Adds the constraints of a `validate` tag to the schema, and returns true if
+// the key is required
+func addConstraints(schema map[string]interface{}, tag string) (bool, error) {
+	if tag == "" { return false, nil }
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, argument := rule, ""
+		if index := strings.Index(rule, "="); index >= 0 { name, argument = rule[0:index], rule[index + 1:len(rule)] }
+		if name == "required" {
+			required = true
+			continue
+		}
+		if name == "oneof" {
+			var values []interface{}
+			for _, text := range strings.Fields(argument) {
+				value, err := parseValueAs(text, 0)
+				if err != nil || schema["type"] == "string" { value = NewString(text) }
+				values = append(values, value.jsonValue())
+			}
+			schema["enum"] = values
+			continue
+		}
+
+		n, err := strconv.ParseFloat(argument, 64)
+		if err != nil || (name != "min" && name != "max" && name != "len") { return false, fmt.Errorf("invalid validate rule %q", rule) }
+		prefix := ""
+		switch schema["type"] {
+		case "string": prefix = "Length"
+		case "array": prefix = "Items"
+		case "object": prefix = "Properties"
+		}
+		if prefix == "" {
+			if name == "len" { return false, fmt.Errorf("invalid validate rule %q", rule) }
+			schema[map[string]string{"min": "minimum", "max": "maximum"}[name]] = n
+			continue
+		}
+		if name == "min" || name == "len" { schema["min" + prefix] = int(n) }
+		if name == "max" || name == "len" { schema["max" + prefix] = int(n) }
+	}
+	return required, nil
+}
+
+// Infers a JSON Schema from sample documents, with the types of their keys
+// merged like in GenerateStruct(). Keys are not required, and other keys are
+// allowed.
+func DocumentSchema(samples ...Document) ([]byte, error) {
+	shape := &goShape{kind: kindTable}
+	for _, sample := range samples {
+		if sample.root == nil { continue }
+		shape.add(sectionValue(sample.root))
+	}
+	schema := shape.schema()
+	schema["$schema"] = schemaVersion
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func (this *goShape) schema() map[string]interface{} {
+	if this.mixed { return map[string]interface{}{} }
+	switch this.kind {
+	case kindBool: return map[string]interface{}{"type": "boolean"}
+	case kindString: return map[string]interface{}{"type": "string"}
+	case kindInt, kindBigInt: return map[string]interface{}{"type": "integer"}
+	case kindFloat: return map[string]interface{}{"type": "number"}
+	case kindDate: return map[string]interface{}{"type": "string", "format": "date-time"}
+	case kindLocalDate: return map[string]interface{}{"type": "string", "format": "date"}
+	case kindLocalTime: return map[string]interface{}{"type": "string", "format": "partial-time"}
+	case kindLocalDateTime: return map[string]interface{}{"type": "string", "format": "partial-date-time"}
+	case kindArray:
+		output := map[string]interface{}{"type": "array"}
+		if this.element.kind != 0 { output["items"] = this.element.schema() }
+		return output
+	case kindTable:
+		properties := make(map[string]interface{})
+		for _, key := range this.keys {
+			properties[key] = this.fields[key].schema()
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	}
+	return map[string]interface{}{}
+}
+
+// Reads the doc comments of the struct types and of their fields in Go source
+// files, or in all the Go files of directories, for StructSchema(). The
+// comments are keyed by "Type" and "Type.Field", and "Type.Field.Field" for
+// the fields of anonymous structs. Line comments after a field are used if
+// it has no doc comment.
+func ParseComments(paths ...string) (map[string]string, error) {
+	output := make(map[string]string)
+	fileSet := token.NewFileSet()
+	for _, path := range paths {
+		files := []string{path}
+		if info, err := os.Stat(path); err != nil {
+			return nil, err
+		} else if info.IsDir() {
+			files, _ = filepath.Glob(filepath.Join(path, "*.go"))
+		}
+		for _, file := range files {
+			source, err := parser.ParseFile(fileSet, file, nil, parser.ParseComments)
+			if err != nil { return nil, err }
+			for _, declaration := range source.Decls {
+				general, ok := declaration.(*ast.GenDecl)
+				if !ok || general.Tok != token.TYPE { continue }
+				for _, spec := range general.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					doc := typeSpec.Doc
+					if doc == nil && len(general.Specs) == 1 { doc = general.Doc }
+					if text := commentText(doc, nil); text != "" { output[typeSpec.Name.Name] = text }
+					if structType, ok := typeSpec.Type.(*ast.StructType); ok { parseFieldComments(output, typeSpec.Name.Name, structType) }
+				}
+			}
+		}
+	}
+	return output, nil
+}
+
+func parseFieldComments(output map[string]string, name string, structType *ast.StructType) {
+	for _, field := range structType.Fields.List {
+		for _, fieldName := range field.Names {
+			if text := commentText(field.Doc, field.Comment); text != "" { output[name + "." + fieldName.Name] = text }
+			fieldType := field.Type
+			for {
+				if pointer, ok := fieldType.(*ast.StarExpr); ok {
+					fieldType = pointer.X
+				} else if array, ok := fieldType.(*ast.ArrayType); ok {
+					fieldType = array.Elt
+				} else if mapType, ok := fieldType.(*ast.MapType); ok {
+					fieldType = mapType.Value
+				} else {
+					break
+				}
+			}
+			if nested, ok := fieldType.(*ast.StructType); ok { parseFieldComments(output, name + "." + fieldName.Name, nested) }
+		}
+	}
+}
+
+func commentText(doc *ast.CommentGroup, comment *ast.CommentGroup) string {
+	if doc == nil { doc = comment }
+	if doc == nil { return "" }
+	return strings.TrimSpace(doc.Text())
+}
@@ -0,0 +1,43 @@
+package toml
+
+// Builds a table from its keys and values, which must have the same length
+func NewTable(keys []string, values []Value) Value {
+	var output Value
+	output.kind = kindTable
+	output.array = make([]Value, 0, len(keys) * 2)
+	for i, key := range keys {
+		output.array = append(output.array, NewString(key), values[i])
+	}
+	return output
+}
+
+// Returns the section as a table, with its sub-sections as nested tables
+func sectionValue(node *Node) Value {
+	if node.kind == kindValue { return node.value }
+	var keys []string
+	var values []Value
+	for _, child := range node.Children() {
+		keys = append(keys, child.name)
+		values = append(values, sectionValue(child))
+	}
+	return NewTable(keys, values)
+}
+
+// Returns the keys of a table, in declaration order
+func (this Value) Keys() []string {
+	if this.kind != kindTable { return nil }
+	output := make([]string, 0, len(this.array) / 2)
+	for i := 0; i + 1 < len(this.array); i += 2 {
+		output = append(output, this.array[i].text)
+	}
+	return output
+}
+
+// Returns the value of the given key of a table
+func (this Value) Get(key string) (Value, bool) {
+	if this.kind != kindTable { return Value{}, false }
+	for i := 0; i + 1 < len(this.array); i += 2 {
+		if this.array[i].text == key { return this.array[i + 1], true }
+	}
+	return Value{}, false
+}
@@ -2,8 +2,18 @@ package main
 
 import (
 	toml ".."
+	"flag"
 	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -41,6 +51,106 @@ func assertTimeEqual(desc string, a time.Time, b time.Time) {
 	fmt.Print(".")
 }
 
+// Decodes and encodes itself, for the MARSHALERS tests
+type logLevel int
+
+func (this *logLevel) UnmarshalTOML(value toml.Value) error {
+	levels := map[string]logLevel{"debug": 0, "info": 1, "error": 2}
+	level, ok := levels[value.AsString()]
+	if !ok { return fmt.Errorf("unknown log level %s", value) }
+	*this = level
+	return nil
+}
+
+func (this logLevel) MarshalTOML() (toml.Value, error) {
+	return toml.NewString([]string{"debug", "info", "error"}[this]), nil
+}
+
+// Decodes a table by itself, for the MARSHALERS tests
+type endpoint struct {
+	URL string
+}
+
+func (this *endpoint) UnmarshalTOML(value toml.Value) error {
+	host, ok := value.Get("host")
+	if !ok { return fmt.Errorf("missing host") }
+	port, _ := value.Get("port")
+	this.URL = fmt.Sprintf("%s:%d", host.AsString(), port.AsInt())
+	return nil
+}
+
+// Plugin configurations, chosen by their type key, for the DECODE HOOKS tests
+type plugin interface {
+	Name() string
+}
+
+type filePlugin struct {
+	Path string
+}
+
+func (this filePlugin) Name() string { return "file:" + this.Path }
+
+func pluginHook(from toml.Kind, to reflect.Type, v toml.Value) (interface{}, error) {
+	if from != toml.KindTable || to != reflect.TypeOf((*plugin)(nil)).Elem() { return nil, nil }
+	kind, _ := v.Get("type")
+	if kind.AsString() != "file" { return nil, fmt.Errorf("unknown plugin type %s", kind) }
+	var output filePlugin
+	err := v.Decode(&output)
+	return output, err
+}
+
+type upperKey string
+
+func (this *upperKey) UnmarshalText(text []byte) error {
+	*this = upperKey(strings.ToUpper(string(text)))
+	return nil
+}
+
+type serverBase struct {
+	Host string
+	Port int
+}
+
+type serverConfig struct {
+	serverBase
+	Weights [3]float64
+	Limits *struct {
+		Max int
+	}
+}
+
+// Bound to command line flags, for the COMMAND LINE FLAGS tests
+type flagConfig struct {
+	Debug bool `toml:"debug"`
+	Timeout time.Duration `toml:"timeout"`
+	Tags []string `toml:"tags"`
+	Database struct {
+		Port int `toml:"port"`
+		Password string `toml:"password,sensitive"`
+	} `toml:"database"`
+}
+
+// Settings of the service
+type schemaConfig struct {
+	// Log level
+	Level string `validate:"oneof=debug info"`
+	Port uint16 `default:"8080" validate:"min=1"`
+	Database *struct {
+		User string `validate:"required,max=16"` // Database user
+	}
+	Backends []serverBase
+}
+
+// Recursive types, for the JSON SCHEMA tests
+type schemaTree struct {
+	Name string
+	Children []schemaTree
+}
+
+type schemaForest struct {
+	Trees []*schemaTree
+}
+
 func main() {
 	// TEST 1
 	
@@ -158,4 +268,1156 @@ func main() {
 	assertStringEqual("Array[0] is correct", v.AsArray()[0].AsString(), "]")
 	
 	assertStringEqual("Strings are UTF-8", "中国", doc.GetString("the.zhong_guo"))
+	
+	// ENVIRONMENT VARIABLES
+	
+	doc = parser.ParseFile("test1.toml")
+	doc, err := doc.ApplyEnv("MYAPP", toml.EnvOptions{Environ: []string{
+		"MYAPP_DATABASE_CONNECTION_MAX=6000",
+		"MYAPP_DATABASE_SERVER=10.0.0.3",
+		"myapp_database_enabled=false",
+		"MYAPP_DATABASE_PORTS=[ 9001, 9002 ]",
+		"MYAPP_FLOATS_PI=3",
+		"MYAPP_OWNER_DOB=2001-02-03T04:05:06Z",
+		"MYAPP_DOESNT_EXIST=1",
+		"OTHER_TITLE=\"not applied\"",
+	}})
+	assertTrue("Environment is applied", err == nil)
+	assertIntEqual("Int is overridden", doc.GetInt("database.connection_max"), 6000)
+	assertStringEqual("Unquoted string is overridden", doc.GetString("database.server"), "10.0.0.3")
+	assertFalse("Bool is overridden", doc.GetBool("database.enabled"))
+	assertIntEqual("Array is overridden", len(doc.GetArray("database.ports")), 2)
+	assertIntEqual("Array is overridden", doc.GetArray("database.ports")[1].AsInt(), 9002)
+	assertFloatEqual("Int is accepted as float", doc.GetFloat("floats.pi"), 3)
+	expectedTime, _ = time.Parse(time.RFC3339, "2001-02-03T04:05:06Z")
+	assertTimeEqual("Date is overridden", doc.GetDate("owner.dob"), expectedTime)
+	assertStringEqual("Other prefixes are ignored", doc.GetString("title"), "TOML Example")
+	
+	_, err = doc.ApplyEnv("MYAPP", toml.EnvOptions{Strict: true, Environ: []string{"MYAPP_DATABASE_ENABLED=true", "MYAPP_DOESNT_EXIST=1"}})
+	unknownErr, ok := err.(*toml.UnknownEnvError)
+	assertTrue("Unknown variables are reported", ok)
+	assertStringEqual("Unknown variables are reported", unknownErr.Names[0], "MYAPP_DOESNT_EXIST")
+	
+	_, err = doc.ApplyEnv("MYAPP", toml.EnvOptions{Environ: []string{"MYAPP_DATABASE_CONNECTION_MAX=lots"}})
+	assertTrue("Invalid value is reported", err != nil)
+	
+	_, err = doc.ApplyEnv("", toml.EnvOptions{Strict: true, Environ: []string{"DATABASE_ENABLED=true"}})
+	assertTrue("Strict mode needs a prefix", err != nil)
+	
+	_, err = doc.ApplyEnv("MYAPP", toml.EnvOptions{Environ: []string{"MYAPP_database_server=b", "MYAPP_DATABASE_SERVER=a"}})
+	assertTrue("Variables that only differ by case are reported", err != nil && strings.Contains(err.Error(), "MYAPP_DATABASE_SERVER and MYAPP_database_server"))
+	colliding := toml.Parser{}.Parse("Port = 1\nport = 2\n[a]\nb_c = 3\n[a_b]\nc = 4\n")
+	_, err = colliding.ApplyEnv("MYAPP", toml.EnvOptions{Environ: []string{"MYAPP_A_B_C=5"}})
+	assertTrue("Keys with the same variable name are reported", err != nil && strings.Contains(err.Error(), "MYAPP_A_B_C matches both") && strings.Contains(err.Error(), "a.b_c") && strings.Contains(err.Error(), "a_b.c"))
+	_, err = colliding.ApplyEnv("MYAPP", toml.EnvOptions{Environ: []string{"MYAPP_PORT=5"}})
+	assertTrue("Keys that only differ by case are reported", err != nil && strings.Contains(err.Error(), "Port") && strings.Contains(err.Error(), "port"))
+	_, err = colliding.ApplyEnv("MYAPP", toml.EnvOptions{CaseSensitive: true, Environ: []string{"MYAPP_port=5"}})
+	assertTrue("Keys that only differ by case are distinct when case-sensitive", err == nil)
+	
+	doc, err = doc.ApplyEnv("myapp", toml.EnvOptions{Separator: "__", CaseSensitive: true, Environ: []string{"myapp__database__connection_max=7000", "MYAPP__DATABASE__CONNECTION_MAX=8000"}})
+	assertIntEqual("Separator and case are configurable", doc.GetInt("database.connection_max"), 7000)
+	
+	// COMMAND LINE FLAGS
+	
+	doc = parser.ParseFile("test1.toml")
+	section, _ := doc.GetSection("owner")
+	node, _ := section.Child("dob")
+	assertStringEqual("Trailing comment is kept", node.Comment(), "First class dates? Why not?")
+	section, _ = doc.GetSection("servers.alpha")
+	assertStringEqual("Comment above section is kept", section.Comment(), "You can indent as you please. Tabs or spaces. TOML don't care.")
+	
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	doc.RegisterFlags(flags)
+	assertStringEqual("Default is the document value", flags.Lookup("database.connection_max").DefValue, "5000")
+	assertStringEqual("Usage is the comment", flags.Lookup("owner.dob").Usage, "First class dates? Why not?")
+	err = flags.Parse([]string{"--database.connection_max=42", "-database.server", "10.0.0.9", "--database.debug", "--clients.data=[[1], [2]]"})
+	assertTrue("Flags are parsed", err == nil)
+	assertIntEqual("Flags are only applied on request", doc.GetInt("database.connection_max"), 5000)
+	flaggedDoc, err := doc.ApplyFlags(flags)
+	assertTrue("Flags are applied", err == nil)
+	assertIntEqual("Int flag is applied", flaggedDoc.GetInt("database.connection_max"), 42)
+	assertStringEqual("String flag is applied", flaggedDoc.GetString("database.server"), "10.0.0.9")
+	assertTrue("Bool flag is applied", flaggedDoc.GetBool("database.debug"))
+	assertIntEqual("Array flag is applied", flaggedDoc.GetArray("clients.data")[1].AsArray()[0].AsInt(), 2)
+	assertFloatEqual("Other values are unchanged", flaggedDoc.GetFloat("floats.pi"), 3.14)
+	assertIntEqual("Original document is unchanged", doc.GetInt("database.connection_max"), 5000)
+	_, err = toml.Parser{}.Parse("[database.connection_max]\nvalue = 1\n").ApplyFlags(flags)
+	assertTrue("Flag that can't be applied", err != nil && strings.Contains(err.Error(), "database.connection_max is a section"))
+	secretDoc, _ := toml.Parser{}.Parse("pin = 1234").MarkSensitive("pin")
+	secretFlags := flag.NewFlagSet("test", flag.ContinueOnError)
+	secretDoc.RegisterFlags(secretFlags)
+	err = secretFlags.Lookup("pin").Value.Set("hunter2")
+	assertStringEqual("Sensitive value in a flag error", err.Error(), "invalid int value")
+	
+	flags = flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	doc.RegisterFlags(flags)
+	err = flags.Parse([]string{"--database.connection_max=true"})
+	assertTrue("Flag type is checked", err != nil)
+	
+	flagConfig := flagConfig{Debug: false, Timeout: time.Second}
+	flagConfig.Database.Port = 5432
+	flagConfig.Database.Password = "hunter2"
+	flags = flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	err = toml.RegisterStructFlags(flags, &flagConfig, map[string]string{"flagConfig.Database.Port": "Database port"})
+	assertTrue("Struct flags are registered", err == nil)
+	assertStringEqual("Struct default is the field value", flags.Lookup("database.port").DefValue, "5432")
+	assertStringEqual("Struct usage is the comment", flags.Lookup("database.port").Usage, "Database port")
+	assertStringEqual("Struct flags are named like the keys", flags.Lookup("timeout").DefValue, "\"1s\"")
+	assertStringEqual("Sensitive default is redacted", flags.Lookup("database.password").DefValue, "\"[redacted]\"")
+	err = flags.Parse([]string{"--database.port=6543", "--debug", "--timeout=1m30s", "--tags=[\"a\", \"b\"]"})
+	assertTrue("Struct flags are parsed", err == nil)
+	assertIntEqual("Int field is set", flagConfig.Database.Port, 6543)
+	assertTrue("Bool field is set", flagConfig.Debug)
+	assertTrue("Duration field is set", flagConfig.Timeout == 90 * time.Second)
+	assertIntEqual("Slice field is set", len(flagConfig.Tags), 2)
+	assertStringEqual("Other fields are unchanged", flagConfig.Database.Password, "hunter2")
+	err = flags.Parse([]string{"--database.port=high"})
+	assertTrue("Struct flag type is checked", err != nil)
+	err = toml.RegisterStructFlags(flags, flagConfig, nil)
+	assertTrue("Struct flags need a pointer", err != nil)
+	
+	// WATCHER
+	
+	watchedFile, _ := ioutil.TempFile("", "toml-go")
+	defer os.Remove(watchedFile.Name())
+	// The file is replaced rather than rewritten, so that the watcher never
+	// reads a partial file
+	replaceFile := func(path string, content string) {
+		temp, _ := ioutil.TempFile(filepath.Dir(path), "toml-go")
+		temp.WriteString(content)
+		temp.Close()
+		os.Rename(temp.Name(), path)
+	}
+	replaceFile(watchedFile.Name(), "[database]\nport = 5432\nhost = \"localhost\"\n")
+	watcher, err := parser.WatchFile(watchedFile.Name(), 10 * time.Millisecond)
+	assertTrue("File is watched", err == nil)
+	assertIntEqual("Initial document is loaded", watcher.Document().GetInt("database.port"), 5432)
+	events := make(chan toml.WatchEvent, 10)
+	watcher.Subscribe(func(event toml.WatchEvent) { events <- event })
+	
+	replaceFile(watchedFile.Name(), "[database]\nport = 5433\nhost = \"localhost\"\nuser = \"admin\"\n")
+	event := <-events
+	assertTrue("Reload succeeded", event.Err == nil)
+	assertIntEqual("Changed keys are reported", len(event.Changed), 2)
+	assertStringEqual("Changed keys are reported", event.Changed[0], "database.port")
+	assertStringEqual("Added keys are reported", event.Changed[1], "database.user")
+	assertIntEqual("New document is published", watcher.Document().GetInt("database.port"), 5433)
+	
+	replaceFile(watchedFile.Name(), "[database]\nport\n")
+	event = <-events
+	assertTrue("Parse error is reported", event.Err != nil)
+	assertIntEqual("Last good document is kept", watcher.Document().GetInt("database.port"), 5433)
+	assertIntEqual("Last good document is kept", event.Document.GetInt("database.port"), 5433)
+	watcher.Close()
+	watcher.Close()
+	assertIntEqual("Watcher can be closed twice", watcher.Document().GetInt("database.port"), 5433)
+	
+	replaceFile(watchedFile.Name(), "port = 5432\n")
+	watcher, err = toml.Parser{MaxInputSize: 32}.WatchFile(watchedFile.Name(), 10 * time.Millisecond)
+	assertTrue("Small file is watched", err == nil)
+	events = make(chan toml.WatchEvent, 10)
+	watcher.Subscribe(func(event toml.WatchEvent) { events <- event })
+	replaceFile(watchedFile.Name(), "port = 5433\n" + strings.Repeat("# Padding\n", 10))
+	event = <-events
+	_, isLimitErr := event.Err.(*toml.LimitError)
+	assertTrue("Input size limit applies to reloads", isLimitErr)
+	assertIntEqual("Last good document is kept", watcher.Document().GetInt("port"), 5432)
+	watcher.Close()
+	
+	// SNAPSHOTS AND BUILDER
+	
+	doc = parser.ParseFile("test1.toml")
+	builder := doc.Builder()
+	assertTrue("Value is set", builder.Set("database.connection_max", toml.NewInt(10)) == nil)
+	assertTrue("Sections are created", builder.Set("new.section.key", toml.NewString("value")) == nil)
+	assertTrue("Value can't replace a section", builder.Set("servers.alpha", toml.NewInt(1)) != nil)
+	assertTrue("Section can't replace a value", builder.Set("title.sub", toml.NewInt(1)) != nil)
+	assertTrue("Value is deleted", builder.Delete("owner.dob"))
+	assertFalse("Missing value isn't deleted", builder.Delete("owner.doesntexist"))
+	assertTrue("Section is deleted", builder.Delete("servers.beta"))
+	newDoc := builder.Document()
+	assertIntEqual("New document has new value", newDoc.GetInt("database.connection_max"), 10)
+	assertStringEqual("New document has new section", newDoc.GetString("new.section.key"), "value")
+	_, ok = newDoc.GetValue("owner.dob")
+	assertFalse("New document has deleted value", ok)
+	_, ok = newDoc.GetSection("servers.alpha")
+	assertTrue("New document has other sections", ok)
+	assertIntEqual("Original document is unchanged", doc.GetInt("database.connection_max"), 5000)
+	_, ok = doc.GetValue("owner.dob")
+	assertTrue("Original document is unchanged", ok)
+	
+	builder.Set("database.connection_max", toml.NewInt(20))
+	assertIntEqual("Built document is unchanged by later edits", newDoc.GetInt("database.connection_max"), 10)
+	assertIntEqual("Builder keeps previous edits", builder.Document().GetInt("database.connection_max"), 20)
+	
+	ports := doc.GetArray("database.ports")
+	ports[0] = toml.NewInt(1)
+	assertIntEqual("Arrays can't be modified", doc.GetArray("database.ports")[0].AsInt(), 8001)
+	
+	section, _ = doc.GetSection("servers")
+	clone := section.Clone()
+	assertIntEqual("Clone has the same children", len(clone.Children()), 2)
+	assertStringEqual("Clone is detached", clone.FullName(), "servers")
+	
+	done := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func(i int) {
+			builder := doc.Builder()
+			for j := 0; j < 10; j++ {
+				assertIntEqual("Concurrent reads", doc.GetInt("database.connection_max"), 5000)
+				_ = doc.String()
+				builder.Set("database.connection_max", toml.NewInt(int64(i * j)))
+			}
+			done <- true
+		}(i)
+	}
+	for i := 0; i < 4; i++ { <-done }
+	
+	// DIFF
+	
+	doc = parser.Parse("title = \"a\"\n[database]\nport = 5432\nhost = \"localhost\"\nenabled = true\n")
+	newDoc = parser.Parse("title = \"a\"\n[database]\nport = \"5433\"\nenabled = false\nuser = \"admin\"\n")
+	changes := toml.Diff(doc, newDoc)
+	assertIntEqual("Changes are found", len(changes), 4)
+	assertStringEqual("Changes are sorted", changes[0].Path, "database.enabled")
+	assertTrue("Modified value is found", changes[0].Type == toml.Modified && changes[0].Old.AsBool() && !changes[0].New.AsBool())
+	assertFalse("Kind is unchanged", changes[0].KindChanged())
+	assertTrue("Removed value is found", changes[1].Path == "database.host" && changes[1].Type == toml.Removed)
+	assertTrue("Kind change is found", changes[2].Path == "database.port" && changes[2].KindChanged())
+	assertTrue("Added value is found", changes[3].Path == "database.user" && changes[3].Type == toml.Added)
+	assertStringEqual("Text output", toml.FormatChanges(changes), "~ database.enabled = true -> false\n- database.host = \"localhost\"\n~ database.port = 5432 -> \"5433\" (int -> string)\n+ database.user = \"admin\"\n")
+	jsonOutput, _ := toml.FormatChangesJSON(changes[3:])
+	assertStringEqual("JSON output", jsonOutput, "[\n  {\n    \"new\": \"admin\",\n    \"newKind\": \"string\",\n    \"path\": \"database.user\",\n    \"type\": \"added\"\n  }\n]\n")
+	assertIntEqual("Same documents have no changes", len(toml.Diff(doc, doc)), 0)
+	
+	// JSON PATCH
+	
+	doc = parser.ParseFile("test1.toml")
+	newDoc, err = doc.ApplyPatch([]byte(`[
+		{"op": "test", "path": "/database/connection_max", "value": 5000},
+		{"op": "replace", "path": "/database/connection_max", "value": 6000},
+		{"op": "replace", "path": "/owner/dob", "value": "2001-02-03T04:05:06Z"},
+		{"op": "replace", "path": "/floats/pi", "value": 3},
+		{"op": "add", "path": "/database/ports/1", "value": 9000},
+		{"op": "add", "path": "/database/ports/-", "value": 9001},
+		{"op": "remove", "path": "/clients/data/1/0"},
+		{"op": "add", "path": "/servers/gamma", "value": {"ip": "10.0.0.3", "dc": "eqdc20"}},
+		{"op": "move", "from": "/servers/beta", "path": "/servers/delta"},
+		{"op": "copy", "from": "/title", "path": "/owner/title"},
+		{"op": "remove", "path": "/database/debug"}
+	]`))
+	assertTrue("Patch is applied", err == nil)
+	assertIntEqual("Value is replaced", newDoc.GetInt("database.connection_max"), 6000)
+	expectedTime, _ = time.Parse(time.RFC3339, "2001-02-03T04:05:06Z")
+	assertTimeEqual("Date stays a date", newDoc.GetDate("owner.dob"), expectedTime)
+	v, _ = newDoc.GetValue("floats.pi")
+	assertStringEqual("Float stays a float", v.String(), "3.0")
+	assertFloatEqual("Float stays a float", v.AsFloat(), 3)
+	v, _ = newDoc.GetValue("database.ports")
+	assertStringEqual("Array elements are added", v.String(), "[8001, 9000, 8001, 8002, 9001]")
+	v, _ = newDoc.GetValue("clients.data")
+	assertStringEqual("Array elements are removed", v.String(), "[[\"gamma\", \"delta\"], [2, 123]]")
+	assertStringEqual("Section is added", newDoc.GetString("servers.gamma.dc"), "eqdc20")
+	assertStringEqual("Section is moved", newDoc.GetString("servers.delta.ip"), "10.0.0.2")
+	_, ok = newDoc.GetValue("servers.beta.ip")
+	assertFalse("Section is moved", ok)
+	assertStringEqual("Value is copied", newDoc.GetString("owner.title"), "TOML Example")
+	_, ok = newDoc.GetValue("database.debug")
+	assertFalse("Value is removed", ok)
+	
+	_, err = doc.ApplyPatch([]byte(`[{"op": "replace", "path": "/title", "value": "changed"}, {"op": "test", "path": "/database/enabled", "value": false}]`))
+	assertTrue("Failed test is reported", err != nil)
+	_, err = doc.ApplyPatch([]byte(`[{"op": "replace", "path": "/owner/dob", "value": "not a date"}]`))
+	assertTrue("Date kind is checked", err != nil)
+	_, err = doc.ApplyPatch([]byte(`[{"op": "remove", "path": "/doesntexist"}]`))
+	assertTrue("Missing path is reported", err != nil)
+	_, err = doc.ApplyPatch([]byte(`[{"op": "test", "path": "/servers/alpha", "value": {"ip": "10.0.0.1", "dc": "eqdc10"}}]`))
+	assertTrue("Sections can be tested", err == nil)
+	assertStringEqual("Original document is unchanged", doc.GetString("title"), "TOML Example")
+	
+	newDoc, err = doc.ApplyMergePatch([]byte(`{"title": "Merged", "owner": {"dob": null, "name": "Someone"}, "servers": {"alpha": {"ip": "10.0.0.9"}}, "clients": 1, "new": {"key": true}}`))
+	assertTrue("Merge patch is applied", err == nil)
+	assertStringEqual("Value is merged", newDoc.GetString("title"), "Merged")
+	assertStringEqual("Nested value is merged", newDoc.GetString("owner.name"), "Someone")
+	_, ok = newDoc.GetValue("owner.dob")
+	assertFalse("Null removes the value", ok)
+	assertStringEqual("Other values are kept", newDoc.GetString("servers.alpha.dc"), "eqdc10")
+	assertIntEqual("Section is replaced by a value", newDoc.GetInt("clients"), 1)
+	assertTrue("Section is created", newDoc.GetBool("new.key"))
+	
+	doc = parser.Parse("point = { x = 1, y = 2 }\n[[fruits]]\nname = \"apple\"\n[[fruits]]\nname = \"banana\"\n")
+	newDoc, err = doc.ApplyPatch([]byte(`[
+		{"op": "test", "path": "/fruits/0/name", "value": "apple"},
+		{"op": "replace", "path": "/fruits/0/name", "value": "cherry"},
+		{"op": "add", "path": "/fruits/1/color", "value": "yellow"},
+		{"op": "add", "path": "/fruits/-", "value": {"name": "date"}},
+		{"op": "replace", "path": "/point/x", "value": 10},
+		{"op": "remove", "path": "/point/y"},
+		{"op": "add", "path": "/shapes", "value": [{"a": 1}]}
+	]`))
+	assertTrue("Patch is applied to tables", err == nil)
+	v, _ = newDoc.GetValue("fruits")
+	assertStringEqual("Keys of arrays of tables are patched", v.String(), `[{ name = "cherry" }, { name = "banana", color = "yellow" }, { name = "date" }]`)
+	v, _ = newDoc.GetValue("point")
+	assertStringEqual("Keys of inline tables are patched", v.String(), "{ x = 10 }")
+	v, _ = newDoc.GetValue("shapes")
+	assertStringEqual("Objects in arrays are tables", v.String(), "[{ a = 1 }]")
+	_, err = doc.ApplyPatch([]byte(`[{"op": "remove", "path": "/fruits/0/color"}]`))
+	assertTrue("Missing table key is reported", err != nil)
+	v, _ = doc.GetValue("fruits")
+	assertStringEqual("Original tables are unchanged", v.String(), `[{ name = "apple" }, { name = "banana" }]`)
+	newDoc, err = doc.ApplyMergePatch([]byte(`{"point": {"y": null, "z": 3}, "fruits": [{"name": "fig"}]}`))
+	assertTrue("Merge patch is applied to tables", err == nil)
+	v, _ = newDoc.GetValue("point")
+	assertStringEqual("Inline table is merged", v.String(), "{ x = 1, z = 3 }")
+	v, _ = newDoc.GetValue("fruits")
+	assertStringEqual("Array of tables is replaced", v.String(), `[{ name = "fig" }]`)
+	
+	// QUERIES
+	
+	doc = parser.Parse("[servers.alpha]\nip = \"10.0.0.1\"\nenabled = true\nport = 80\n[servers.beta]\nip = \"10.0.0.2\"\nenabled = false\n[servers.beta.admin]\nport = 8080\n[database]\nport = 5432\nports = [ 8001, 8002, 8003 ]\ndata = [ [\"a\", \"b\"], [1, 2] ]\n")
+	matches, err := doc.Query("servers.*.ip")
+	assertTrue("Query is valid", err == nil)
+	assertIntEqual("Wildcard matches", len(matches), 2)
+	assertStringEqual("Wildcard matches", matches[0].Path, "servers.alpha.ip")
+	assertStringEqual("Wildcard matches", matches[1].Value.AsString(), "10.0.0.2")
+	matches, _ = doc.Query("**.port")
+	assertIntEqual("Descendants match", len(matches), 3)
+	assertStringEqual("Descendants match in declaration order", matches[0].Path, "servers.alpha.port")
+	assertStringEqual("Descendants match in declaration order", matches[1].Path, "servers.beta.admin.port")
+	assertStringEqual("Descendants match in declaration order", matches[2].Path, "database.port")
+	matches, _ = doc.Query("servers[?enabled==true].ip")
+	assertIntEqual("Filter matches", len(matches), 1)
+	assertStringEqual("Filter matches", matches[0].Value.AsString(), "10.0.0.1")
+	matches, _ = doc.Query("servers[?enabled != true]")
+	assertIntEqual("Filter returns sections", len(matches), 1)
+	assertStringEqual("Filter returns sections", matches[0].Node.FullName(), "servers.beta")
+	matches, _ = doc.Query("database.ports[1]")
+	assertStringEqual("Index matches", matches[0].Path, "database.ports[1]")
+	assertIntEqual("Index matches", matches[0].Value.AsInt(), 8002)
+	matches, _ = doc.Query("database.ports[-1]")
+	assertIntEqual("Negative index matches", matches[0].Value.AsInt(), 8003)
+	matches, _ = doc.Query("database.ports[?@ > 8001]")
+	assertIntEqual("Array filter matches", len(matches), 2)
+	matches, _ = doc.Query("database.data[*][0]")
+	assertIntEqual("Nested arrays match", len(matches), 2)
+	assertStringEqual("Nested arrays match", matches[1].Path, "database.data[1][0]")
+	matches, _ = doc.Query("servers.gamma.ip")
+	assertIntEqual("Missing path matches nothing", len(matches), 0)
+	query := toml.MustCompileQuery("**[?port>=1000].port")
+	assertIntEqual("Compiled query can be reused", len(query.Find(doc)), 2)
+	assertIntEqual("Compiled query can be reused", len(query.Find(parser.ParseFile("test1.toml"))), 0)
+	_, err = toml.CompileQuery("servers[?enabled]")
+	assertTrue("Invalid query is reported", err != nil)
+	_, err = toml.CompileQuery("servers..ip")
+	assertTrue("Invalid query is reported", err != nil)
+	
+	doc = parser.Parse("[[fruits]]\nname = \"apple\"\nenabled = true\n[fruits.physical]\ncolor = \"red\"\n[[fruits]]\nname = \"banana\"\nenabled = false\n[shape]\npoint = { x = 1, y = 2 }\n")
+	matches, _ = doc.Query("fruits[0].name")
+	assertIntEqual("Index matches tables", len(matches), 1)
+	assertStringEqual("Index matches tables", matches[0].Path, "fruits[0].name")
+	assertStringEqual("Index matches tables", matches[0].Value.AsString(), "apple")
+	matches, _ = doc.Query("fruits[-1].name")
+	assertStringEqual("Negative index matches tables", matches[0].Value.AsString(), "banana")
+	matches, _ = doc.Query("fruits[0].physical.color")
+	assertStringEqual("Sub-sections of tables match", matches[0].Value.AsString(), "red")
+	matches, _ = doc.Query("fruits[?enabled==true].name")
+	assertIntEqual("Filter matches tables", len(matches), 1)
+	assertStringEqual("Filter matches tables", matches[0].Value.AsString(), "apple")
+	matches, _ = doc.Query("fruits[?physical.color==\"red\"]")
+	assertIntEqual("Filter matches nested keys of tables", len(matches), 1)
+	assertStringEqual("Filter matches nested keys of tables", matches[0].Path, "fruits[0]")
+	matches, _ = doc.Query("**.name")
+	assertIntEqual("Descendants match in tables", len(matches), 2)
+	assertStringEqual("Descendants match in tables", matches[1].Path, "fruits[1].name")
+	matches, _ = doc.Query("shape.point.x")
+	assertIntEqual("Inline tables match", len(matches), 1)
+	assertIntEqual("Inline tables match", matches[0].Value.AsInt(), 1)
+	matches, _ = doc.Query("shape.point.*")
+	assertIntEqual("Wildcard matches inline tables", len(matches), 2)
+	assertStringEqual("Wildcard matches inline tables", matches[1].Path, "shape.point.y")
+	matches, _ = doc.Query("**[?x>=1].y")
+	assertIntEqual("Descendant filters match inline tables", len(matches), 1)
+	
+	// WALK
+	
+	doc = parser.Parse("title = \"a\"\n[b]\nz = [1, [2, 3]]\n[b.c]\nsecret = \"x\"\n[a]\nkey = true\n")
+	var visited []string
+	err = doc.Visit(toml.Visitor{
+		Pre: func(path []string, node *toml.Node, value *toml.Value) error {
+			visited = append(visited, "pre:" + strings.Join(path, "."))
+			return nil
+		},
+		Post: func(path []string, node *toml.Node, value *toml.Value) error {
+			if value == nil { visited = append(visited, "post:" + strings.Join(path, ".")) }
+			return nil
+		},
+	})
+	assertTrue("Walk succeeded", err == nil)
+	assertStringEqual("Walk is in declaration order", strings.Join(visited, " "), "pre:title pre:b pre:b.z pre:b.z.0 pre:b.z.1 pre:b.z.1.0 pre:b.z.1.1 pre:b.c pre:b.c.secret post:b.c post:b pre:a pre:a.key post:a")
+	
+	visited = nil
+	doc.Walk(func(path []string, node *toml.Node, value *toml.Value) error {
+		visited = append(visited, strings.Join(path, "."))
+		if node.FullName() == "b.z" || node.FullName() == "b.c" { return toml.SkipSubtree }
+		return nil
+	})
+	assertStringEqual("Subtrees are skipped", strings.Join(visited, " "), "title b b.z b.c a a.key")
+	
+	visited = nil
+	err = doc.Walk(func(path []string, node *toml.Node, value *toml.Value) error {
+		visited = append(visited, strings.Join(path, "."))
+		if value != nil && value.AsString() == "x" { return toml.StopWalk }
+		return nil
+	})
+	assertTrue("Walk is stopped without error", err == nil)
+	assertStringEqual("Walk is stopped", visited[len(visited) - 1], "b.c.secret")
+	
+	err = doc.Walk(func(path []string, node *toml.Node, value *toml.Value) error { return fmt.Errorf("error") })
+	assertTrue("Walk error is returned", err != nil && err.Error() == "error")
+	
+	section, _ = doc.GetSection("b")
+	visited = nil
+	section.Walk(func(path []string, node *toml.Node, value *toml.Value) error {
+		if value != nil && value.AsInt() == 2 { visited = append(visited, strings.Join(path, ".")) }
+		return nil
+	})
+	assertStringEqual("Node walk has full paths", strings.Join(visited, " "), "b.z.1.0")
+	
+	doc = parser.Parse("point = { x = 1, y = [2] }\n[[fruits]]\nname = \"apple\"\n[fruits.physical]\ncolor = \"red\"\n[[fruits]]\nname = \"banana\"\n")
+	visited = nil
+	doc.Walk(func(path []string, node *toml.Node, value *toml.Value) error {
+		visited = append(visited, strings.Join(path, "."))
+		return nil
+	})
+	assertStringEqual("Walk descends into tables", strings.Join(visited, " "), "point point.x point.y point.y.0 fruits fruits.0 fruits.0.name fruits.0.physical fruits.0.physical.color fruits.1 fruits.1.name")
+	
+	// POSITIONS
+	
+	doc = parser.ParseFile("test1.toml")
+	section, _ = doc.GetSection("servers.alpha")
+	assertStringEqual("Section header position", section.Position().String(), "test1.toml:21:3")
+	assertIntEqual("Section header range", section.Position().End - section.Position().Offset, len("[servers.alpha]"))
+	section, _ = doc.GetSection("database")
+	node, _ = section.Child("connection_max")
+	assertStringEqual("Key position", node.Position().String(), "test1.toml:14:1")
+	v, _ = doc.GetValue("database.connection_max")
+	assertStringEqual("Value position", v.Position().String(), "test1.toml:14:18")
+	assertIntEqual("Value range", v.Position().End - v.Position().Offset, 4)
+	v, _ = doc.GetValue("multilinearray.test")
+	assertStringEqual("Multi-line value position", v.Position().String(), "test1.toml:33:8")
+	assertStringEqual("Array element position", v.AsArray()[2].Position().String(), "test1.toml:36:2")
+	v, _ = doc.GetValue("clients.data")
+	assertStringEqual("Nested array element position", v.AsArray()[1].AsArray()[2].Position().String(), "test1.toml:30:37")
+	
+	doc = parser.Parse("[the]\nzhong_guo = \"中国\" # comment\nafter = 1")
+	v, _ = doc.GetValue("the.after")
+	assertStringEqual("Position without file", v.Position().String(), "3:9")
+	assertIntEqual("Byte offset", v.Position().Offset, len("[the]\nzhong_guo = \"中国\" # comment\nafter = "))
+	assertFalse("Built values have no position", toml.NewInt(1).Position().IsValid())
+	
+	// ERROR REPORTING
+	
+	broken := "a = 1\nb = 80x\n\n[server\nname = \"alpha\"\na = 2\n[ok]\nc = [1, 2\n[..]\nd = true\n\tjunk\n[last]\ne = 5"
+	doc, err = parser.TryParse(broken)
+	assertTrue("First error is returned", err != nil)
+	errors := err.(toml.ErrorList)
+	assertIntEqual("Parsing stops at the first error", len(errors), 1)
+	assertStringEqual("First error", errors[0].Error(), "2:7: unexpected text after the value of b")
+	assertIntEqual("First error code", int(errors[0].Code), int(toml.ErrInvalidValue))
+	assertStringEqual("Snippet", errors[0].Snippet(), "b = 80x\n      ^")
+	_, ok = doc.GetValue("a")
+	assertTrue("Document before the error is returned", ok)
+	_, ok = doc.GetValue("ok.c")
+	assertFalse("Document after the error is not parsed", ok)
+	
+	allErrors := toml.Parser{AllErrors: true}
+	doc, err = allErrors.TryParse(broken)
+	errors = err.(toml.ErrorList)
+	var codes []string
+	for _, e := range errors { codes = append(codes, e.Code.String() + "@" + e.Position.String()) }
+	assertStringEqual("All errors are reported", strings.Join(codes, " "), "invalid-value@2:7 invalid-header@4:1 duplicate-key@6:1 invalid-value@8:5 invalid-header@9:1 invalid-line@11:2")
+	assertStringEqual("Error list message", errors.Error(), "2:7: unexpected text after the value of b (and 5 more errors)")
+	assertStringEqual("Snippet keeps tabs", errors[5].Snippet(), "\tjunk\n\t^")
+	v, _ = doc.GetValue("a")
+	assertIntEqual("First definition is kept", int(v.AsInt()), 1)
+	v, _ = doc.GetValue("last.e")
+	assertIntEqual("Parsing resumes at the next header", int(v.AsInt()), 5)
+	_, ok = doc.GetValue("d")
+	assertFalse("Keys under an invalid header are skipped", ok)
+	
+	doc, err = allErrors.TryParse("a = [\n  1,\n  \"x=y\", # ]\n]\n[b] # section\nc = \"unterminated\n")
+	errors = err.(toml.ErrorList)
+	assertIntEqual("Multi-line arrays are not errors", len(errors), 1)
+	assertStringEqual("Unterminated string", errors[0].Error(), "6:5: unterminated value for c")
+	v, _ = doc.GetValue("a")
+	assertIntEqual("Multi-line array with a key-like string", len(v.AsArray()), 2)
+	section, _ = doc.GetSection("b")
+	assertStringEqual("Comment after a section header", section.Comment(), "section")
+	
+	_, err = parser.TryParse("a = [1, 2]\nb = []\nc = [1, 2,]")
+	assertTrue("Empty arrays and trailing commas are valid", err == nil)
+	_, err = parser.TryParseFile("test2.toml")
+	assertTrue("Valid file has no errors", err == nil)
+	_, err = parser.TryParseFile("test1.toml")
+	assertTrue("Invalid escape is reported", err != nil && err.(toml.ErrorList)[0].Position.String() == "test1.toml:49:21")
+	
+	// LIMITS
+	
+	limited := toml.Parser{MaxDepth: 3, MaxArrayLength: 3, MaxStringLength: 5, MaxKeys: 4, MaxKeyLength: 5}
+	limitError := func(s string) string {
+		_, err := limited.TryParse(s)
+		if err == nil { return "" }
+		limitErr, ok := err.(*toml.LimitError)
+		if !ok { return "not a limit error: " + err.Error() }
+		return limitErr.Error()
+	}
+	assertStringEqual("Within limits", limitError("[a]\nb = [[1, 2], \"abcde\"]\n"), "")
+	assertStringEqual("Section depth", limitError("[a.b.c.d]\n"), "1:1: MaxDepth of 3 exceeded")
+	assertStringEqual("Array depth", limitError("[a.b]\nc = [[1]]\n"), "2:6: MaxDepth of 3 exceeded")
+	assertStringEqual("Array length", limitError("a = [\n  1,\n  2,\n  3,\n  4\n]"), "5:3: MaxArrayLength of 3 exceeded")
+	assertStringEqual("Trailing comma", limitError("a = [1, 2, 3,]"), "")
+	assertStringEqual("String length", limitError("a = [\"ok\", \"abcdef\"]"), "1:12: MaxStringLength of 5 exceeded")
+	assertStringEqual("Unterminated string length", limitError("a = \"abcdefgh"), "1:5: MaxStringLength of 5 exceeded")
+	assertStringEqual("Key count", limitError("a = 1\nb = 2\n[c.d]\ne = 3"), "4:1: MaxKeys of 4 exceeded")
+	assertStringEqual("Key length", limitError("abcdef = 1"), "1:1: MaxKeyLength of 5 exceeded")
+	assertStringEqual("Section name length", limitError("[abcdef]"), "1:1: MaxKeyLength of 5 exceeded")
+	assertStringEqual("Inline table key count", limitError("a = { b = 1, c = 2, d = 3, e = 4 }"), "1:28: MaxKeys of 4 exceeded")
+	assertStringEqual("Inline table key length", limitError("a = { abcdef = 1 }"), "1:7: MaxKeyLength of 5 exceeded")
+	_, err = toml.Parser{}.TryParse("a = { b = 1, c = 2, b = 3 }")
+	assertTrue("Inline table duplicate key", err != nil)
+	
+	_, err = toml.Parser{MaxInputSize: 10}.TryParse("a = \"0123456789\"")
+	assertTrue("Input size", err != nil && err.Error() == "MaxInputSize of 10 exceeded")
+	_, err = toml.Parser{MaxInputSize: 10}.TryParseFile("test1.toml")
+	assertTrue("File size", err != nil && err.Error() == "test1.toml: MaxInputSize of 10 exceeded")
+	_, err = toml.Parser{MaxInputSize: 100000}.TryParseFile("test2.toml")
+	assertTrue("File within size", err == nil)
+	
+	func() {
+		defer func() {
+			r := recover()
+			assertTrue("Parse panics on limits", r != nil && r.(string) == "1:1: MaxKeyLength of 5 exceeded")
+		}()
+		limited.Parse("abcdef = 1")
+	}()
+	
+	deep := strings.Repeat("[", 100000) + strings.Repeat("]", 100000)
+	_, err = limited.TryParse("a = " + deep)
+	assertTrue("Deep nesting is rejected before parsing", err != nil)
+	
+	// COMPACT VALUES
+	
+	doc = parser.Parse("a = [ 1,  2 ] # comment\nb = \"x\\ty\"\nc = 1.5\nd = 1979-05-27T07:32:00Z")
+	v, _ = doc.GetValue("a")
+	assertStringEqual("Raw text of a parsed value", v.Raw(), "[ 1,  2 ]")
+	assertStringEqual("Raw text of an element", v.AsArray()[1].Raw(), "2")
+	v, _ = parser.Parse("[[users]]\nname = \"a\"\n[[users]]\nname = \"b\"\n").GetValue("users")
+	assertStringEqual("Raw text of an array of tables", v.Raw(), v.String())
+	assertStringEqual("Raw text of a table of an array", v.AsArray()[1].Raw(), `{ name = "b" }`)
+	assertTrue("Position of an array of tables", !v.Position().IsValid() && !v.AsArray()[0].Position().IsValid())
+	v, _ = doc.GetValue("b")
+	assertStringEqual("Raw text of a string", v.Raw(), "\"x\\ty\"")
+	assertStringEqual("Raw text of a built value", toml.NewArray(toml.NewInt(1), toml.NewInt(2)).Raw(), "[1, 2]")
+	v, _ = doc.GetValue("c")
+	assertIntEqual("Only the active payload is read", int(v.AsInt64()), 0)
+	assertFalse("Float is not a bool", v.AsBool())
+	assertStringEqual("Float is not a string", v.AsString(), "")
+	v, _ = doc.GetValue("d")
+	assertStringEqual("Date is not a string", v.AsString(), "")
+	assertTrue("Parsed date is in UTC", v.AsDate().Location() == time.UTC)
+	assertTrue("Bool and int share the payload", toml.NewInt(1).AsBool() == false && toml.NewBool(true).AsInt() == 0)
+	
+	zoned := time.Date(2014, 3, 1, 10, 30, 0, 500, time.FixedZone("CET", 3600))
+	v = toml.NewDate(zoned)
+	assertTrue("Date with a zone", v.AsDate().Equal(zoned))
+	zoneName, zoneOffset := v.AsDate().Zone()
+	assertStringEqual("Zone name is kept", zoneName, "CET")
+	assertIntEqual("Zone offset is kept", zoneOffset, 3600)
+	assertIntEqual("Nanoseconds are kept", v.AsDate().Nanosecond(), 500)
+	assertTrue("Negative Unix time", toml.NewDate(time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC)).AsDate().Year() == 1950)
+	
+	discard := toml.Parser{DiscardSource: true}
+	doc = discard.Parse("a = [ 1,  2 ]\nb = \"text\"")
+	v, _ = doc.GetValue("a")
+	assertStringEqual("Raw text without source", v.Raw(), "[1, 2]")
+	assertFalse("No position without source", v.Position().IsValid())
+	v, _ = doc.GetValue("b")
+	assertStringEqual("Strings are kept without source", v.AsString(), "text")
+	_, err = discard.TryParse("a = 1\nb = x")
+	assertTrue("Errors have positions without source", err != nil && err.Error() == "2:5: invalid value for b")
+	
+	// INTEGERS
+	
+	_, err = parser.TryParse("id = 99999999999999999999\nsmall = -9223372036854775808")
+	assertTrue("Overflow is an error", err != nil && err.(toml.ErrorList)[0].Code == toml.ErrIntegerOverflow)
+	assertStringEqual("Overflow position", err.(toml.ErrorList)[0].Position.String(), "1:6")
+	_, err = parser.TryParse("ids = [1, 99999999999999999999]")
+	assertTrue("Overflow in an array", err != nil && err.(toml.ErrorList)[0].Position.String() == "1:11")
+	func() {
+		defer func() { assertTrue("Parse panics on overflow", recover() != nil) }()
+		parser.Parse("id = 99999999999999999999")
+	}()
+	
+	big := toml.Parser{BigIntegers: true}
+	doc, err = big.TryParse("id = 99999999999999999999\nneg = -00099999999999999999999\nsmall = 42\nids = [1, 99999999999999999999]")
+	assertTrue("Big integers are parsed", err == nil)
+	v, _ = doc.GetValue("id")
+	assertStringEqual("Big integer", v.AsBigInt().String(), "99999999999999999999")
+	assertStringEqual("Big integer string", v.String(), "99999999999999999999")
+	assertIntEqual("Big integer doesn't fit", int(v.AsInt64()), 0)
+	_, err = v.Int64()
+	assertTrue("Big integer out of int64 range", err != nil && err.Error() == "toml: 99999999999999999999 is out of range for int64")
+	json, _ := v.MarshalJSON()
+	assertStringEqual("Big integer in JSON", string(json), "99999999999999999999")
+	v, _ = doc.GetValue("neg")
+	assertStringEqual("Big integer is normalized", v.String(), "-99999999999999999999")
+	v, _ = doc.GetValue("small")
+	assertIntEqual("Small integers are not big", int(v.AsInt64()), 42)
+	assertStringEqual("Small integer as big integer", v.AsBigInt().String(), "42")
+	matches, _ = doc.Query("ids[?@ > 10]")
+	assertIntEqual("Big integers are compared", len(matches), 1)
+	var unsigned struct {
+		Max uint64 `toml:"max"`
+		Small uint8 `toml:"small"`
+	}
+	err = big.Parse("max = 18446744073709551615\nsmall = 255").Decode(&unsigned)
+	assertTrue("Decode big integer into uint64", err == nil && unsigned.Max == math.MaxUint64 && unsigned.Small == 255)
+	err = big.Parse("max = 18446744073709551616").Decode(&unsigned)
+	assertStringEqual("Big integer out of uint64 range", err.Error(), "1:7: max: 18446744073709551616 is out of range for uint64")
+	err = big.Parse("small = 18446744073709551615").Decode(&unsigned)
+	assertTrue("Big integer out of uint8 range", err != nil)
+	
+	v = toml.NewInt(300)
+	_, err = v.Int8()
+	assertTrue("Int8 out of range", err != nil && err.Error() == "toml: 300 is out of range for int8")
+	i16, err := v.Int16()
+	assertTrue("Int16 in range", err == nil && i16 == 300)
+	_, err = toml.NewInt(-2147483649).Int32()
+	assertTrue("Int32 out of range", err != nil)
+	i32, err := toml.NewInt(-2147483648).Int32()
+	assertTrue("Int32 minimum", err == nil && i32 == -2147483648)
+	_, err = toml.NewString("1").Int()
+	assertTrue("Int of a string", err != nil && err.Error() == "toml: expected int value, got string")
+	_, err = toml.NewFloat(1e300).Float32()
+	assertTrue("Float32 out of range", err != nil)
+	f32, err := toml.NewFloat(1.5).Float32()
+	assertTrue("Float32 in range", err == nil && f32 == 1.5)
+	assertIntEqual("Unchecked conversion truncates", int(toml.NewInt(300).AsInt8()), 44)
+	
+	// FLOATS
+	
+	doc = toml.Parser{}.Parse("rate = 0.1\nthreshold = 1e-9\nprice = 1.50\nbig = +1.5E3\nmax = inf\nmin = -inf\nundefined = nan\n")
+	v, _ = doc.GetValue("rate")
+	assertStringEqual("Float spelling is kept", v.String(), "0.1")
+	v, _ = doc.GetValue("threshold")
+	assertStringEqual("Float exponent is kept", v.String(), "1e-9")
+	assertFloatEqual("Float with exponent", v.AsFloat64(), 1e-9)
+	assertStringEqual("Float as decimal", v.AsDecimalString(), "0.000000001")
+	v, _ = doc.GetValue("price")
+	assertStringEqual("Trailing zeros are kept", v.String(), "1.50")
+	assertStringEqual("Trailing zeros in decimal", v.AsDecimalString(), "1.50")
+	v, _ = doc.GetValue("big")
+	assertFloatEqual("Float with signed exponent", v.AsFloat64(), 1500)
+	assertStringEqual("Exponent in decimal", v.AsDecimalString(), "1500")
+	v, _ = doc.GetValue("max")
+	assertTrue("Infinity", v.AsFloat64() > 1e308)
+	assertStringEqual("Infinity has no decimal", v.AsDecimalString(), "")
+	v, _ = doc.GetValue("min")
+	assertTrue("Negative infinity", v.AsFloat64() < -1e308)
+	v, _ = doc.GetValue("undefined")
+	assertTrue("NaN", v.AsFloat64() != v.AsFloat64())
+	assertStringEqual("NaN string", v.String(), "nan")
+	json, _ = doc.MarshalJSON()
+	assertTrue("NaN in JSON", strings.Contains(string(json), `"undefined":"nan"`))
+	
+	assertStringEqual("Built float", toml.NewFloat(1).String(), "1.0")
+	assertStringEqual("Built small float", toml.NewFloat(1e-9).String(), "1e-9")
+	assertStringEqual("Built large float", toml.NewFloat(1e21).String(), "1e21")
+	assertStringEqual("Built infinity", toml.NewFloat(math.Inf(-1)).String(), "-inf")
+	assertStringEqual("Built float as decimal", toml.NewFloat(0.25).AsDecimalString(), "0.25")
+	assertStringEqual("Int as decimal", toml.NewInt(-7).AsDecimalString(), "-7")
+	assertStringEqual("Backslash is escaped once", toml.NewString(`a\nb`).String(), `"a\\nb"`)
+	
+	for _, invalid := range []string{"--1.5", "+-2.0", "+.5", "1.", ".5", "1.e5", "1e", "1e+", "1.5.2"} {
+		_, err = toml.ParseValue(invalid)
+		assertTrue("Invalid float is rejected: " + invalid, err != nil)
+	}
+	for _, valid := range []string{"+1.5", "-0.5", "1e5", "1E-5", "-2.5e+3", "+inf", "-nan"} {
+		v, err = toml.ParseValue(valid)
+		assertTrue("Valid float is accepted: " + valid, err == nil)
+		assertStringEqual("Valid float is written as is", v.String(), valid)
+	}
+	
+	changes = toml.Diff(toml.Parser{}.Parse("a = 0.1\nb = nan"), toml.Parser{}.Parse("a = 1e-1\nb = nan"))
+	assertIntEqual("Same float with another spelling is unchanged", len(changes), 0)
+	
+	// DATES
+	
+	doc = toml.Parser{}.Parse("offset = 1979-05-27T00:32:00.999999-07:00\nutc = 1979-05-27 07:32:00z\nday = 1979-05-27\nalarm = 07:32:00.500\nmeeting = 1979-05-27T07:32:00\nlong = 07:32:00.1234567891")
+	v, _ = doc.GetValue("offset")
+	assertStringEqual("Offset and precision are kept", v.String(), "1979-05-27T00:32:00.999999-07:00")
+	_, offset := v.AsDate().Zone()
+	assertIntEqual("Offset of a date", offset, -7 * 3600)
+	assertIntEqual("Fractional seconds", v.AsDate().Nanosecond(), 999999000)
+	v, _ = doc.GetValue("utc")
+	assertStringEqual("Date with a space", v.String(), "1979-05-27T07:32:00Z")
+	v, _ = doc.GetValue("day")
+	assertStringEqual("Local date", v.String(), "1979-05-27")
+	assertTrue("Local date is not an offset date", v.AsDate().IsZero())
+	assertIntEqual("Local date day", doc.GetLocalDate("day").Day, 27)
+	v, _ = doc.GetValue("alarm")
+	assertStringEqual("Local time keeps its precision", v.String(), "07:32:00.500")
+	assertIntEqual("Local time", v.AsLocalTime().Nanosecond, 500000000)
+	assertStringEqual("Local date-time", doc.GetLocalDateTime("meeting").String(), "1979-05-27T07:32:00")
+	v, _ = doc.GetValue("long")
+	assertStringEqual("Precision beyond nanoseconds is truncated", v.String(), "07:32:00.123456789")
+	_, err = toml.Parser{}.TryParse("a = 1979-02-30")
+	assertTrue("Invalid day", err != nil)
+	_, err = toml.Parser{}.TryParse("a = 24:00:00")
+	assertTrue("Invalid hour", err != nil)
+	assertStringEqual("Built local time", toml.NewLocalTime(toml.LocalTime{Hour: 7, Minute: 5, Nanosecond: 2500000, Precision: 6}).String(), "07:05:00.002500")
+	json, _ = doc.MarshalJSON()
+	assertTrue("Local date in JSON", strings.Contains(string(json), `"day":"1979-05-27"`))
+	
+	// DECODING
+	
+	type server struct {
+		Host string
+		Ports []uint16 `toml:"ports"`
+		Weight float64
+	}
+	var config struct {
+		Offset time.Time
+		Day toml.LocalDate
+		Meeting time.Time
+		Alarm toml.LocalTime `toml:"alarm"`
+		Alpha server `toml:"alpha"`
+		Ignored string `toml:"-"`
+	}
+	doc = toml.Parser{}.Parse("offset = 1979-05-27T00:32:00Z\nday = 1979-05-27\nmeeting = 1979-05-27T07:32:00\nalarm = 07:32:00\n[alpha]\nhost = \"10.0.0.1\"\nports = [80, 443]\nweight = 2\n")
+	err = doc.Decode(&config)
+	assertTrue("Decode", err == nil)
+	assertIntEqual("Decode offset date", config.Offset.Hour(), 0)
+	assertIntEqual("Decode local date", config.Day.Year, 1979)
+	assertTrue("Decode local date-time into time.Time", config.Meeting.Equal(time.Date(1979, 5, 27, 7, 32, 0, 0, time.Local)))
+	assertIntEqual("Decode local time", config.Alarm.Minute, 32)
+	assertStringEqual("Decode by field name", config.Alpha.Host, "10.0.0.1")
+	assertIntEqual("Decode slice", int(config.Alpha.Ports[1]), 443)
+	assertFloatEqual("Decode int into float", config.Alpha.Weight, 2)
+	err = toml.Parser{}.Parse("[alpha]\nports = [80, 70000]").Decode(&config)
+	assertStringEqual("Decode out of range", err.Error(), "2:14: alpha.ports[1]: 70000 is out of range for uint16")
+	err = toml.Parser{}.Parse("day = \"monday\"").Decode(&config)
+	assertStringEqual("Decode wrong kind", err.Error(), "1:7: day: cannot decode string into toml.LocalDate")
+	err = toml.Parser{}.Parse("").Decode(config)
+	assertTrue("Decode needs a pointer", err != nil)
+	
+	// DURATIONS AND SIZES
+	
+	doc = toml.Parser{}.Parse("timeout = \"1m30s\"\nretry = 5\nmax_body = \"10MiB\"\ncache = \"1.5 GB\"\nsmall = 512\nbad = \"10 parsecs\"")
+	assertTrue("Get duration", doc.GetDuration("timeout") == 90 * time.Second)
+	assertTrue("Get byte size", doc.GetByteSize("max_body") == 10 * toml.MiB)
+	assertTrue("Decimal byte size", doc.GetByteSize("cache") == 1500 * toml.MB)
+	assertTrue("Byte size of an integer", doc.GetByteSize("small") == 512)
+	v, _ = doc.GetValue("bad")
+	_, err = v.ByteSize()
+	assertStringEqual("Invalid byte size", err.Error(), `toml: invalid byte size: "10 parsecs"`)
+	_, err = v.Duration()
+	assertTrue("Invalid duration", err != nil)
+	assertStringEqual("Byte size string", (1536 * toml.KiB).String(), "1536KiB")
+	
+	var limits struct {
+		Timeout time.Duration
+		Retry time.Duration
+		MaxBody toml.ByteSize `toml:"max_body"`
+	}
+	_, err = toml.DecoderConfig{DurationUnit: time.Second}.Decode(doc, &limits)
+	assertTrue("Decode durations", err == nil && limits.Timeout == 90 * time.Second && limits.Retry == 5 * time.Second)
+	assertTrue("Decode byte size", limits.MaxBody == 10 * toml.MiB)
+	err = toml.Parser{}.Parse("timeout = \"soon\"").Decode(&limits)
+	assertStringEqual("Decode invalid duration", err.Error(), `1:11: timeout: invalid duration: "soon"`)
+	
+	doc, err = toml.Encode(limits)
+	assertTrue("Encode", err == nil)
+	assertStringEqual("Encode durations and sizes", doc.String(), "Timeout = \"1m30s\"\n\nRetry = \"5s\"\n\nmax_body = \"10MiB\"\n\n")
+	limits.Timeout = 0
+	err = doc.Decode(&limits)
+	assertTrue("Encoded document is decoded back", err == nil && limits.Timeout == 90 * time.Second)
+	_, err = toml.Encode(42)
+	assertTrue("Encode needs a struct", err != nil)
+	
+	// MARSHALERS
+	
+	var logging struct {
+		Level logLevel `toml:"level"`
+		Listen net.IP `toml:"listen"`
+		Hosts []net.IP `toml:"hosts"`
+	}
+	doc = toml.Parser{}.Parse("level = \"error\"\nlisten = \"10.0.0.1\"\nhosts = [\"::1\"]")
+	err = doc.Decode(&logging)
+	assertTrue("Decode with Unmarshaler", err == nil && logging.Level == 2)
+	assertStringEqual("Decode with TextUnmarshaler", logging.Listen.String(), "10.0.0.1")
+	assertStringEqual("Decode array with TextUnmarshaler", logging.Hosts[0].String(), "::1")
+	err = toml.Parser{}.Parse("\nlevel = \"verbose\"").Decode(&logging)
+	assertStringEqual("Unmarshaler error", err.Error(), `2:9: level: unknown log level "verbose"`)
+	err = toml.Parser{}.Parse("listen = \"10.0.0\"").Decode(&logging)
+	assertTrue("TextUnmarshaler error", err != nil && strings.HasPrefix(err.Error(), "1:10: listen: "))
+	var endpoints struct {
+		Inline endpoint `toml:"inline"`
+		Section endpoint `toml:"section"`
+	}
+	_, err = toml.DecoderConfig{Strict: true}.Decode(toml.Parser{}.Parse("inline = { host = \"a\", port = 1 }\n[section]\nhost = \"b\"\nport = 2\n"), &endpoints)
+	assertTrue("Unmarshaler of an inline table", err == nil && endpoints.Inline.URL == "a:1")
+	assertStringEqual("Unmarshaler of a section", endpoints.Section.URL, "b:2")
+	err = toml.Parser{}.Parse("[section]\nport = 2\n").Decode(&endpoints)
+	assertStringEqual("Unmarshaler error of a section", err.Error(), "1:1: section: missing host")
+	doc, err = toml.Encode(logging)
+	assertTrue("Encode with Marshaler", err == nil && doc.GetString("level") == "error")
+	assertStringEqual("Encode with TextMarshaler", doc.GetString("listen"), "10.0.0.1")
+	
+	// STRICT DECODING
+	
+	var database struct {
+		Database struct {
+			ConnectionMax int `toml:"connection_max"`
+			Enabled bool
+		}
+	}
+	doc = toml.Parser{}.Parse("[database]\nconection_max = 5000\nenabled = true\n[database.replica]\nhost = \"b\"\n[cache]\nsize = 1\n")
+	metaData, err := toml.DecoderConfig{}.Decode(doc, &database)
+	assertTrue("Undecoded keys are not an error", err == nil && database.Database.Enabled)
+	assertStringEqual("Undecoded keys", strings.Join(metaData.Undecoded(), ","), "database.conection_max,database.replica,cache")
+	assertTrue("Key is defined", metaData.IsDefined("database.replica.host"))
+	assertFalse("Key is not defined", metaData.IsDefined("database.connection_max"))
+	assertStringEqual("Kind of a key", metaData.Kind("cache.size").String(), "int")
+	assertStringEqual("Kind of a section", metaData.Kind("database.replica").String(), "section")
+	assertIntEqual("All keys", len(metaData.Keys()), 7)
+	_, err = toml.DecoderConfig{Strict: true}.Decode(doc, &database)
+	assertStringEqual("Strict decoding", err.Error(), "2:1: database.conection_max: unknown key")
+	section, _ = doc.GetSection("database")
+	metaData, err = toml.DecoderConfig{}.DecodeSection(section, &database.Database)
+	assertStringEqual("Undecoded keys of a section", strings.Join(metaData.Undecoded(), ","), "database.conection_max,database.replica")
+	
+	var fleet struct {
+		Servers []struct {
+			Host string
+		}
+		Point struct {
+			X int
+		}
+		Extra map[string]interface{}
+	}
+	doc = toml.Parser{}.Parse("point = { x = 1, z = 2 }\nextra = { any = 1 }\n[[servers]]\nhost = \"a\"\n[[servers]]\nhots = \"b\"\n")
+	metaData, err = toml.DecoderConfig{}.Decode(doc, &fleet)
+	assertTrue("Tables are decoded", err == nil && fleet.Point.X == 1)
+	assertStringEqual("Undecoded keys of tables", strings.Join(metaData.Undecoded(), ","), "point.z,servers[1].hots")
+	assertStringEqual("Keys of tables", strings.Join(metaData.Keys(), ","), "point,point.x,point.z,extra,extra.any,servers,servers[0].host,servers[1].hots")
+	assertTrue("Key of a table is defined", metaData.IsDefined("servers[1].hots"))
+	assertFalse("Key of a table is not defined", metaData.IsDefined("servers[2].host"))
+	assertStringEqual("Kind of a key of a table", metaData.Kind("point.x").String(), "int")
+	assertStringEqual("Kind of a table", metaData.Kind("servers[0]").String(), "table")
+	_, err = toml.DecoderConfig{Strict: true}.Decode(doc, &fleet)
+	assertStringEqual("Strict decoding of tables", err.Error(), "1:22: point.z: unknown key")
+	
+	// DECODE HOOKS
+	
+	var hooked struct {
+		Pattern *regexp.Regexp
+		Network net.IPNet
+		Subnet net.IPNet
+		Home url.URL
+		Proxy *url.URL
+		Tags []string
+		Ports []int
+		Timeout time.Duration
+		Output plugin
+	}
+	patternHook := func(from toml.Kind, to reflect.Type, v toml.Value) (interface{}, error) {
+		if from != toml.KindString || to != reflect.TypeOf((*regexp.Regexp)(nil)) { return nil, nil }
+		return regexp.Compile(v.AsString())
+	}
+	decoder := toml.DecoderConfig{Hooks: []toml.DecodeHook{patternHook, toml.IPHook, toml.URLHook, toml.SliceHook(","), toml.DurationHook(time.Second), pluginHook}}
+	doc = toml.Parser{}.Parse("pattern = \"^a+$\"\nnetwork = \"10.0.0.0/8\"\nsubnet = [\"192.168.1.7\", \"255.255.255.0\"]\nhome = \"https://example.com/a\"\nproxy = \"http://proxy:3128\"\ntags = \"a, b\"\nports = \"80,443\"\ntimeout = 1.5\n[output]\ntype = \"file\"\npath = \"/tmp/log\"\n")
+	metaData, err = decoder.Decode(doc, &hooked)
+	assertTrue("Decode with hooks", err == nil)
+	assertTrue("Custom hook", hooked.Pattern.MatchString("aaa"))
+	assertStringEqual("CIDR hook", hooked.Network.String(), "10.0.0.0/8")
+	assertStringEqual("Address and mask hook", hooked.Subnet.String(), "192.168.1.0/24")
+	assertStringEqual("URL hook", hooked.Home.Host, "example.com")
+	assertStringEqual("URL pointer hook", hooked.Proxy.Port(), "3128")
+	assertStringEqual("Slice hook", strings.Join(hooked.Tags, "|"), "a|b")
+	assertIntEqual("Slice hook with integers", hooked.Ports[1], 443)
+	assertTrue("Duration hook", hooked.Timeout == 1500 * time.Millisecond)
+	assertStringEqual("Section hook", hooked.Output.Name(), "file:/tmp/log")
+	assertIntEqual("Section decoded by a hook", len(metaData.Undecoded()), 0)
+	_, err = decoder.Decode(toml.Parser{}.Parse("[output]\ntype = \"http\""), &hooked)
+	assertStringEqual("Hook error", err.Error(), `1:1: output: unknown plugin type "http"`)
+	_, err = decoder.Decode(toml.Parser{}.Parse("pattern = \"(\""), &hooked)
+	assertTrue("Custom hook error", err != nil && strings.HasPrefix(err.Error(), "1:11: pattern: error parsing regexp"))
+	v = toml.NewTable([]string{"a", "b"}, []toml.Value{toml.NewInt(1), toml.NewArray(toml.NewString("x"))})
+	assertStringEqual("Table string", v.String(), `{ a = 1, b = ["x"] }`)
+	
+	// DEFAULTS
+	
+	var defaulted struct {
+		Host string `toml:"host" default:"localhost"`
+		Port int `toml:"port" default:"8080"`
+		Ports []int `default:"[1, 2]"`
+		Since toml.LocalDate `default:"2020-01-31"`
+		Timeout time.Duration `default:"30s"`
+		Quoted string `default:"\"a # b\""`
+		Database struct {
+			User string `default:"admin"`
+			Max int `toml:"connection_max" default:"10"`
+		} `toml:"database"`
+	}
+	err = toml.Parser{}.Parse("port = 80\n[database]\nconnection_max = 5000\n").Decode(&defaulted)
+	assertTrue("Decode with defaults", err == nil)
+	assertStringEqual("Default string", defaulted.Host, "localhost")
+	assertIntEqual("Default is not used for a defined key", defaulted.Port, 80)
+	assertIntEqual("Default array", defaulted.Ports[1], 2)
+	assertIntEqual("Default date", defaulted.Since.Day, 31)
+	assertTrue("Default duration", defaulted.Timeout == 30 * time.Second)
+	assertStringEqual("Default quoted string", defaulted.Quoted, "a # b")
+	assertStringEqual("Default in a section", defaulted.Database.User, "admin")
+	assertIntEqual("Key in a section", defaulted.Database.Max, 5000)
+	defaulted.Database.Max = 0
+	err = toml.Parser{}.Parse("").Decode(&defaulted)
+	assertIntEqual("Default in a missing section", defaulted.Database.Max, 10)
+	defaulted.Port = 9000
+	defaulted.Database.User = "reader"
+	err = toml.Parser{}.Parse("").Decode(&defaulted)
+	assertIntEqual("Default doesn't replace a pre-populated field", defaulted.Port, 9000)
+	assertStringEqual("Default doesn't replace a pre-populated field in a section", defaulted.Database.User, "reader")
+	var badDefault struct {
+		Port int `default:"eighty"`
+	}
+	err = toml.Parser{}.Parse("").Decode(&badDefault)
+	assertStringEqual("Invalid default", err.Error(), "toml: Port: cannot decode string into int")
+	
+	defaults := toml.Parser{}.Parse("title = \"Default\"\n[database]\nuser = \"root\"\nconnection_max = 10\n[cache]\nsize = 1\n")
+	doc = toml.Parser{}.Parse("[database]\nconnection_max = 5000\n").WithDefaults(defaults)
+	assertStringEqual("Merged default value", doc.GetString("title"), "Default")
+	assertStringEqual("Merged section", doc.GetString("database.user"), "root")
+	assertIntEqual("Value wins over default", doc.GetInt("database.connection_max"), 5000)
+	assertIntEqual("Merged default section", doc.GetInt("cache.size"), 1)
+	node, _ = doc.GetSection("database")
+	assertIntEqual("Merged section keeps its position", node.Position().Line, 1)
+	defaulted.Database.User = ""
+	_, err = toml.DecoderConfig{Defaults: defaults}.Decode(toml.Parser{}.Parse("port = 1"), &defaulted)
+	assertStringEqual("Decode with a defaults document", defaulted.Database.User, "root")
+	metaData, err = toml.DecoderConfig{Defaults: defaults, Strict: true}.Decode(toml.Parser{}.Parse("port = 1\n[database]\nuser = \"admin\"\n"), &defaulted)
+	assertTrue("Keys only in the defaults are not unknown", err == nil)
+	assertTrue("Keys only in the defaults are not defined", !metaData.IsDefined("title") && !metaData.IsDefined("database.connection_max"))
+	assertTrue("Keys of the document are defined", metaData.IsDefined("port") && metaData.IsDefined("database.user"))
+	assertIntEqual("Metadata of the document", len(metaData.Keys()), 3)
+	assertIntEqual("Decoded keys of the document", len(metaData.Undecoded()), 0)
+	_, err = toml.DecoderConfig{Defaults: defaults, Strict: true}.Decode(toml.Parser{}.Parse("port = 1\nextra = 2\n"), &defaulted)
+	assertStringEqual("Unknown key of the document with defaults", err.Error(), "2:1: extra: unknown key")
+	
+	// COLLECTIONS
+	
+	doc = toml.Parser{}.Parse(`point = { x = 1, y = 2, tags = ["a"] }
+[[servers]]
+host = "alpha"
+weights = [0.5, 1.5]
+[servers.limits]
+max = 3
+[[servers]]
+host = "beta"
+port = 81
+[by_name.alpha]
+port = 8080
+[by_name.beta]
+host = "b"
+`)
+	v, _ = doc.GetValue("point")
+	assertStringEqual("Inline table", v.String(), `{ x = 1, y = 2, tags = ["a"] }`)
+	assertIntEqual("Array of tables", len(doc.GetArray("servers")), 2)
+	v, _ = doc.GetArray("servers")[0].Get("limits")
+	assertStringEqual("Table in an array of tables", v.String(), "{ max = 3 }")
+	_, err = toml.Parser{}.TryParse("a = { x = 1, x = 2 }")
+	assertStringEqual("Duplicate inline key", err.Error(), "1:14: invalid value for a")
+	_, err = toml.Parser{}.TryParse("[[a]]\n[a]")
+	assertStringEqual("Section already defined as an array of tables", err.Error(), "2:1: a is already defined as an array of tables")
+	
+	var collections struct {
+		Point struct {
+			X, Y int
+			Tags []string
+		}
+		Servers []serverConfig
+		ByName map[string]serverConfig `toml:"by_name"`
+		Any interface{} `toml:"point"`
+		Missing *serverConfig
+	}
+	collections.ByName = map[string]serverConfig{"alpha": {serverBase: serverBase{Host: "a"}}, "gamma": {}}
+	err = doc.Decode(&collections)
+	assertTrue("Decode collections", err == nil)
+	assertIntEqual("Inline table into a struct", collections.Point.Y, 2)
+	assertIntEqual("Array of tables into a slice", len(collections.Servers), 2)
+	assertStringEqual("Embedded struct", collections.Servers[0].Host, "alpha")
+	assertIntEqual("Embedded struct in a second table", collections.Servers[1].Port, 81)
+	assertFloatEqual("Fixed array", collections.Servers[0].Weights[1], 1.5)
+	assertFloatEqual("Fixed array padded with zeros", collections.Servers[0].Weights[2], 0)
+	assertIntEqual("Pointer to an existing section", collections.Servers[0].Limits.Max, 3)
+	assertTrue("Pointer to a missing section", collections.Servers[1].Limits == nil && collections.Missing == nil)
+	assertStringEqual("Map merged with existing entry", collections.ByName["alpha"].Host, "a")
+	assertIntEqual("Map entry decoded", collections.ByName["alpha"].Port, 8080)
+	assertStringEqual("New map entry", collections.ByName["beta"].Host, "b")
+	_, ok = collections.ByName["gamma"]
+	assertTrue("Existing map entry kept", ok)
+	assertIntEqual("Interface from a table", int(collections.Any.(map[string]interface{})["x"].(int64)), 1)
+	assertStringEqual("Interface from an array", collections.Any.(map[string]interface{})["tags"].([]interface{})[0].(string), "a")
+	var keys struct {
+		ByName map[upperKey]interface{} `toml:"by_name"`
+	}
+	err = doc.Decode(&keys)
+	_, ok = keys.ByName["ALPHA"]
+	assertTrue("TextUnmarshaler map key", ok)
+	var small struct {
+		Weights [1]float64
+	}
+	err = toml.Parser{}.Parse("weights = [1, 2]").Decode(&small)
+	assertStringEqual("Fixed array too short", err.Error(), "1:11: weights: 2 elements don't fit in [1]float64")
+	var anything map[string]interface{}
+	err = doc.Decode(&anything)
+	assertIntEqual("Document into a map", len(anything), 3)
+	assertStringEqual("Section into a map", anything["by_name"].(map[string]interface{})["beta"].(map[string]interface{})["host"].(string), "b")
+	
+	doc, err = toml.Encode(collections)
+	assertTrue("Encode collections", err == nil)
+	assertStringEqual("Encode embedded struct", doc.GetString("by_name.beta.Host"), "b")
+	v, _ = doc.GetArray("Servers")[1].Get("Host")
+	assertStringEqual("Encode array of structs", v.AsString(), "beta")
+	
+	untyped := map[string]interface{}{}
+	err = toml.Parser{}.Parse("a = \nb = 1\n").Decode(&untyped)
+	assertTrue("Invalid value is reported", err != nil && strings.Contains(err.Error(), "a: invalid value"))
+	
+	// GENERATED STRUCTS
+	
+	source, err := toml.GenerateStruct("config", "Config",
+		toml.Parser{}.Parse("title = \"x\"\nrate = 1\nserver_ids = [1, 2]\nsince = 1979-05-27\n[database]\nmax = 1\n[[servers]]\nname = \"a\"\n[servers.limits]\nmax = 1\n[[servers]]\nname = \"b\""),
+		toml.Parser{}.Parse("rate = 1.5\nmixed = 1\n[database]\nuser = \"root\""),
+		toml.Parser{}.Parse("mixed = \"a\""))
+	assertTrue("Generate struct", err == nil)
+	generated := strings.Join(strings.Fields(string(source)), " ")
+	assertTrue("Generated package", strings.HasPrefix(generated, `package config import ( "time" ) type Config struct {`))
+	assertTrue("Generated string", strings.Contains(generated, "Title string `toml:\"title\"`"))
+	assertTrue("Generated float from int and float", strings.Contains(generated, "Rate float64 `toml:\"rate\"`"))
+	assertTrue("Generated slice", strings.Contains(generated, "ServerIDs []int64 `toml:\"server_ids\"`"))
+	assertTrue("Generated date", strings.Contains(generated, "Since time.Time `toml:\"since\"`"))
+	assertTrue("Generated merged section", strings.Contains(generated, "Database *struct { Max int64 `toml:\"max\"` User string `toml:\"user\"` } `toml:\"database\"`"))
+	assertTrue("Generated array of tables", strings.Contains(generated, "Servers []struct { Name string `toml:\"name\"` Limits *struct { Max int64 `toml:\"max\"` } `toml:\"limits\"` } `toml:\"servers\"`"))
+	assertTrue("Generated mixed kinds", strings.Contains(generated, "Mixed interface{} `toml:\"mixed\"`"))
+	_, err = toml.GenerateStruct("config", "Config")
+	assertTrue("Generate without samples", err != nil)
+	
+	// JSON SCHEMA
+	
+	comments, err := toml.ParseComments("main.go")
+	assertTrue("Parse comments", err == nil)
+	assertStringEqual("Type comment", comments["schemaConfig"], "Settings of the service")
+	assertStringEqual("Field comment", comments["schemaConfig.Level"], "Log level")
+	assertStringEqual("Line comment in an anonymous struct", comments["schemaConfig.Database.User"], "Database user")
+	source, err = toml.StructSchema(&schemaConfig{}, comments)
+	assertTrue("Struct schema", err == nil)
+	generated = strings.Join(strings.Fields(string(source)), " ")
+	assertTrue("Schema version", strings.Contains(generated, `"$schema": "http://json-schema.org/draft-07/schema#"`))
+	assertTrue("Schema description", strings.Contains(generated, `"Level": { "description": "Log level", "enum": [ "debug", "info" ], "type": "string" }`))
+	assertTrue("Schema default and minimum", strings.Contains(generated, `"Port": { "default": 8080, "minimum": 1, "type": "integer" }`))
+	assertTrue("Schema required key", strings.Contains(generated, `"required": [ "User" ]`))
+	assertTrue("Schema string length", strings.Contains(generated, `"maxLength": 16`))
+	assertTrue("Schema array of structs", strings.Contains(generated, `"Backends": { "items": { "additionalProperties": false, "properties": { "Host": { "type": "string" }, "Port": { "type": "integer" } }, "type": "object" }, "type": "array" }`))
+	source, err = toml.StructSchema(&schemaTree{}, nil)
+	assertTrue("Recursive struct schema", err == nil)
+	generated = strings.Join(strings.Fields(string(source)), " ")
+	assertTrue("Recursive struct is referenced", strings.Contains(generated, `"Children": { "items": { "$ref": "#" }, "type": "array" }`))
+	source, err = toml.StructSchema(schemaForest{}, nil)
+	assertTrue("Nested recursive struct schema", err == nil)
+	generated = strings.Join(strings.Fields(string(source)), " ")
+	assertTrue("Nested recursive struct is referenced", strings.Contains(generated, `"Trees": { "items": { "$ref": "#/definitions/schemaTree" }, "type": "array" }`))
+	assertTrue("Nested recursive struct is defined", strings.Contains(generated, `"definitions": { "schemaTree": { "additionalProperties": false, "properties": { "Children": { "items": { "$ref": "#/definitions/schemaTree" }, "type": "array" }, "Name": { "type": "string" } }, "type": "object" } }`))
+	_, err = toml.StructSchema(struct{ N int `validate:"max=x"` }{}, nil)
+	assertStringEqual("Invalid validate tag", err.Error(), `toml: N: invalid validate rule "max=x"`)
+	source, err = toml.DocumentSchema(toml.Parser{}.Parse("a = 1\nb = [1, 2.5]\n[c]\nd = 1979-05-27"))
+	generated = strings.Join(strings.Fields(string(source)), " ")
+	assertTrue("Document schema", strings.Contains(generated, `"a": { "type": "integer" }, "b": { "items": { "type": "number" }, "type": "array" }, "c": { "properties": { "d": { "format": "date", "type": "string" } }, "type": "object" }`))
+	
+	// SENSITIVE VALUES
+	
+	doc = toml.Parser{}.Parse("name = \"app\"\n[database]\npassword = \"hunter2\"\nport = 5432\n[api.keys]\ntoken = \"abc\"\n")
+	doc, err = doc.MarkSensitive("*.password", "**.token")
+	assertTrue("Mark sensitive", err == nil)
+	v, _ = doc.GetValue("database.password")
+	assertTrue("Sensitive value", v.IsSensitive())
+	assertStringEqual("Sensitive value string", v.String(), `"[redacted]"`)
+	assertStringEqual("Sensitive value raw text", v.Raw(), `"[redacted]"`)
+	assertStringEqual("Sensitive value accessor", doc.GetString("database.password"), "hunter2")
+	assertStringEqual("Revealed value", v.Reveal().String(), `"hunter2"`)
+	node, _ = doc.GetSection("database")
+	assertStringEqual("Sensitive value in a section", node.String(), "[database]\npassword = \"[redacted]\"\nport = 5432\n")
+	assertTrue("Sensitive value at any depth", strings.Contains(doc.String(), `token = "[redacted]"`))
+	assertTrue("Revealed document", strings.Contains(doc.Reveal().String(), `password = "hunter2"`))
+	json, _ = doc.MarshalJSON()
+	assertTrue("Sensitive value in JSON", strings.Contains(string(json), `"password":"[redacted]"`) && !strings.Contains(string(json), "hunter2"))
+	builder = doc.Builder()
+	builder.Set("database.password", toml.NewString("hunter3"))
+	changes = toml.Diff(doc, builder.Document())
+	assertIntEqual("Sensitive value change", len(changes), 1)
+	assertStringEqual("Sensitive value in a diff", toml.FormatChanges(changes), "~ database.password = \"[redacted]\" -> \"[redacted]\"\n")
+	v = toml.Sensitive(toml.NewArray(toml.NewString("a")))
+	assertTrue("Sensitive array elements", v.AsArray()[0].IsSensitive())
+	doc, _ = doc.MarkSensitive("database.port")
+	_, err = doc.ApplyEnv("APP", toml.EnvOptions{Environ: []string{"APP_DATABASE_PORT=secret"}})
+	assertStringEqual("Sensitive value in an environment error", err.Error(), "toml: environment variable APP_DATABASE_PORT: invalid int value")
+	doc, _ = doc.ApplyEnv("APP", toml.EnvOptions{Environ: []string{"APP_DATABASE_PORT=6543"}})
+	v, _ = doc.GetValue("database.port")
+	assertTrue("Sensitive value replaced by the environment", v.IsSensitive() && v.AsInt() == 6543)
+	
+	var secrets struct {
+		User string
+		Pin int8 `toml:"pin,sensitive"`
+	}
+	err = toml.Parser{}.Parse("pin = 1234").Decode(&secrets)
+	assertStringEqual("Sensitive value in a decode error", err.Error(), "1:7: pin: [redacted] is out of range for int8")
+	secrets.Pin = 12
+	doc, _ = toml.Encode(secrets)
+	assertTrue("Encode sensitive field", strings.Contains(doc.String(), `pin = "[redacted]"`))
+	v, _ = doc.Reveal().GetValue("pin")
+	assertStringEqual("Encode and reveal sensitive field", v.String(), "12")
+	
+	doc, _ = toml.Parser{}.Parse("users = [{ name = \"a\", token = \"tok-secret\" }]\nlevel = { key = \"hidden\" }\n").MarkSensitive("**.token", "level.key")
+	assertTrue("Sensitive keys of arrays of tables", !strings.Contains(doc.String(), "tok-secret") && strings.Contains(doc.String(), `name = "a"`))
+	assertTrue("Sensitive keys of inline tables", !strings.Contains(doc.String(), "hidden"))
+	v, _ = doc.GetArray("users")[0].Get("token")
+	assertTrue("Sensitive key of an inline table", v.IsSensitive() && v.AsString() == "tok-secret")
+	doc, _ = toml.Parser{}.Parse("[db]\nlogin = { user = \"a\", password = \"hunter2\" }\nhosts = [\"a\", \"hunter2\"]\n").MarkSensitive("db.login.password", "db.hosts[1]")
+	v, _ = doc.GetValue("db.login")
+	assertStringEqual("Raw text of a table with a sensitive key", v.Raw(), `{ user = "a", password = "[redacted]" }`)
+	v, _ = doc.GetValue("db.hosts")
+	assertStringEqual("Raw text of an array with a sensitive element", v.Raw(), `["a", "[redacted]"]`)
+	doc, _ = toml.Parser{}.Parse("[db]\npassword = \"hunter2\"\nhosts = [\"a\"]\n").MarkSensitive("db.password", "db.hosts")
+	newDoc, err = doc.ApplyPatch([]byte(`[{"op": "test", "path": "/db/password", "value": "hunter2"}, {"op": "replace", "path": "/db/password", "value": "hunter3"}, {"op": "add", "path": "/db/hosts/-", "value": "b"}]`))
+	assertTrue("Patch sensitive values", err == nil && newDoc.GetString("db.password") == "hunter3")
+	assertTrue("Patched values stay sensitive", !strings.Contains(newDoc.String(), "hunter3") && !strings.Contains(newDoc.String(), `"b"`))
+	_, err = doc.ApplyPatch([]byte(`[{"op": "test", "path": "/db/password", "value": "[redacted]"}]`))
+	assertTrue("Test redacted value", err != nil)
+	newDoc, err = doc.ApplyMergePatch([]byte(`{"db": {"password": "hunter4"}}`))
+	assertTrue("Merged values stay sensitive", err == nil && newDoc.GetString("db.password") == "hunter4" && !strings.Contains(newDoc.String(), "hunter4"))
+	var endpoint struct {
+		URL *url.URL `toml:"url,sensitive"`
+		IP net.IP `toml:"ip,sensitive"`
+		Level logLevel `toml:"level,sensitive"`
+	}
+	_, err = toml.DecoderConfig{Hooks: []toml.DecodeHook{toml.URLHook}}.Decode(toml.Parser{}.Parse("url = \"postgres://u:hunter2@h/%zz\""), &endpoint)
+	assertStringEqual("Sensitive value in a hook error", err.Error(), "1:7: url: invalid URL \"[redacted]\"")
+	err = toml.Parser{}.Parse("ip = \"hunter2\"").Decode(&endpoint)
+	assertStringEqual("Sensitive value in a text unmarshaler error", err.Error(), "1:6: ip: invalid string value \"[redacted]\"")
+	err = toml.Parser{}.Parse("level = \"hunter2\"").Decode(&endpoint)
+	assertStringEqual("Sensitive value in an unmarshaler error", err.Error(), "1:9: level: invalid string value \"[redacted]\"")
 }
@@ -4,13 +4,15 @@ package toml
 
 import (
 	"strings"
-	"io/ioutil"
 	"fmt"
 	"strconv"
 	"time"
+	"sort"
+	"math"
+	"math/big"
 )
 
-type Kind int
+type Kind uint8
 
 const (
 	kindRoot = 1
@@ -21,39 +23,98 @@ const (
 	kindInt = 6
 	kindFloat = 7
 	kindArray = 8
-	kindDate = 9
+	kindDate = 9 // Offset date-time
+	kindBigInt = 10
+	kindLocalDate = 11
+	kindLocalTime = 12
+	kindLocalDateTime = 13
+	kindTable = 14
 )
 
+// The kinds of values, for decode hooks. Sections are seen as tables.
+const (
+	KindBool Kind = kindBool
+	KindString Kind = kindString
+	KindInt Kind = kindInt
+	KindBigInt Kind = kindBigInt
+	KindFloat Kind = kindFloat
+	KindArray Kind = kindArray
+	KindTable Kind = kindTable
+	KindDate Kind = kindDate // Offset date-time
+	KindLocalDate Kind = kindLocalDate
+	KindLocalTime Kind = kindLocalTime
+	KindLocalDateTime Kind = kindLocalDateTime
+)
+
+func (this Kind) String() string {
+	switch this {
+	case kindRoot: return "root"
+	case kindSection: return "section"
+	case kindValue: return "value"
+	case kindBool: return "bool"
+	case kindString: return "string"
+	case kindInt: return "int"
+	case kindFloat: return "float"
+	case kindArray: return "array"
+	case kindDate: return "date"
+	case kindBigInt: return "bigint"
+	case kindLocalDate: return "local date"
+	case kindLocalTime: return "local time"
+	case kindLocalDateTime: return "local datetime"
+	case kindTable: return "table"
+	}
+	return "undefined"
+}
+
+// Limits are disabled when zero
 type Parser struct {
-	
+	AllErrors bool // Keep parsing after an error, and report all of them
+	MaxInputSize int // In bytes
+	MaxDepth int // Nesting of sections and arrays, eg. 4 for x = [[1]] in [a.b]
+	MaxArrayLength int
+	MaxStringLength int // In bytes, as written in the document
+	MaxKeys int // Number of keys and sections in the document
+	MaxKeyLength int // In bytes, also applies to section names
+	DiscardSource bool // Don't keep the document text, which Value.Raw() and the positions need
+	BigIntegers bool // Keep the integers that don't fit in an int64 as big integers instead of failing
 }
 
 type Node struct {
 	name string
 	value Value
 	kind Kind
-	Children map[string]*Node
+	comment string
+	children map[string]*Node // The children by name, only for the nodes that have many
+	order []*Node // The children, in declaration order
 	parent *Node
+	source *sourceFile
+	offset int // Byte range of the key or section header
+	end int
 }
 
+// Only the fields used by the kind of the value are set. Positions are
+// limited to the first 4 GB of a document.
 type Value struct {
-	raw string
 	kind Kind
-	asBool bool
-	asInt int64
-	asFloat float64
-	asString string
-	asArray []Value
-	asDate time.Time
+	flags uint8 // Number of digits of the fractional seconds of a date (0 if unknown), and flagSensitive
+	zone int16 // Offset of a date from UTC, in minutes
+	offset uint32 // Byte range of the value
+	end uint32
+	nsec int32 // Nanoseconds of a date
+	scalar uint64 // Bool (0 or 1), int64, float64 bits, Unix time of a date (as if in UTC for local ones), or seconds of a local time
+	text string // String, digits of a big integer, float as written, or zone name of a date
+	array []Value // Elements of an array, or names and values of a table, alternating
+	source *sourceFile
 }
 
 type Document struct {
 	root *Node
 }
 
-func cleanRawValue(s string) string {
+// Returns the raw value and its trailing comment, if any
+func cleanRawValue(s string) (string, string) {
 	s = strings.Trim(s, " \t\n\r")
-	if len(s) == 0 { return "" }
+	if len(s) == 0 { return "", "" }
 	
 	inString := false
 	escape := false
@@ -72,278 +133,248 @@ func cleanRawValue(s string) string {
 			continue
 		}
 		if c == '#' && !inString {
-			return strings.Trim(s[0:i], " \t\n\r")
+			return strings.Trim(s[0:i], " \t\n\r"), strings.Trim(s[i + 1:len(s)], " \t\n\r")
 		}
 	}
 	
-	return s
+	return s, ""
 }
 
+// Parses the value at the start of s. On error, returns the index of the
+// offending character.
 func parseValue(s string) (Value, int, bool) {
-	var v Value
-	if len(s) == 0 { return v, 0, false }
-	
-	if strings.Index(s, "true") == 0 || strings.Index(s, "false") == 0 {
-		v.kind = kindBool
-		v.asBool = strings.Index(s, "true") == 0
-		index := 4
-		if !v.asBool { index = 5 }
-		v.raw = s[0:index]
-		return v, index, true
-	}
-	
-	if s[0] == '"' {
-		parsed, index, ok := parseString(s)
-		if !ok { return v, 0, false }
-		v.asString = parsed
-		v.kind = kindString
-		v.raw = s[0:index]
-		return v, index, true
-	}
-	
-	if s[0] == '[' {
-		parsed, index, ok := parseArray(s)
-		if !ok { return v, 0, false }
-		v.asArray = parsed
-		v.kind = kindArray
-		v.raw = s[0:index]
-		return v, index, true
-	}
-	
-	if len(s) >= 20 && s[19] == 'Z' {
-		parsed, index, ok := parseDate(s)
-		if !ok { return v, 0, false }
-		v.asDate = parsed
-		v.kind = kindDate
-		v.raw = s[0:index]
-		return v, index, true
-	}
-	
-	numString, index, ok := parseNumber(s)
-	if !ok { return v, 0, false }
-	
-	parsedInt, err := strconv.ParseInt(numString, 10, 64)
-	if err == nil {
-		v.asInt = parsedInt
-		v.kind = kindInt
-		v.raw = s[0:index]
-		return v, index, true
+	var state parseState
+	return state.value(s, 0, 0)
+}
+
+// Parses a value given outside of a TOML file (environment variable, command
+// line flag...) and checks that it is of the expected kind. Strings don't need
+// to be quoted. If kind is 0, any kind is accepted.
+func parseValueAs(s string, kind Kind) (Value, error) {
+	s = strings.Trim(s, " \t\n\r")
+	var state parseState
+	state.parser.BigIntegers = kind == kindBigInt
+	v, index, ok := state.value(s, 0, 0)
+	complete := ok && index == len(s)
+
+	if kind == kindString || (kind == 0 && !complete) {
+		if complete && v.kind == kindString { return v, nil }
+		return NewString(s), nil
 	}
-	
-	parsedFloat, err := strconv.ParseFloat(numString, 64)
-	if err == nil {
-		v.asFloat = parsedFloat
+
+	if !complete { return v, fmt.Errorf("invalid %s value: %q", kind, s) }
+	if kind == 0 || v.kind == kind { return v, nil }
+
+	if kind == kindFloat && v.kind == kindInt {
 		v.kind = kindFloat
-		v.raw = s[0:index]
-		return v, index, true
+		v.scalar = math.Float64bits(float64(int64(v.scalar)))
+		return v, nil
 	}
-	
-	return v, 0, false
-}
 
-func parseDate(s string) (time.Time, int, bool) {
-	timeString := s[0:20]
-	output, err := time.Parse(time.RFC3339, timeString)
-	if err != nil { return output, 0, false }
-	return output, 20, true
-}
+	if kind == kindBigInt && v.kind == kindInt { return NewBigInt(v.AsBigInt()), nil }
 
-func parseNumber(s string) (string, int, bool) {
-	numberString := ""
-	allowedChars := "0123456789.-"
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if !strings.Contains(allowedChars, string(c)) {
-			break
-		}
-		numberString += string(c)
-	}
-	if len(numberString) <= 0 { return "", 0, false }
-	return numberString, len(numberString), true
+	return v, fmt.Errorf("expected %s value, got %s: %q", kind, v.kind, s)
 }
 
-func parseArray(s string) ([]Value, int, bool) {
-	var output []Value
-	if len(s) <= 0 { return output, 0, false }
-
-	endIndex := 0
-	state := 0 // 0 = start, 1 = before value, 2 = end value
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-						
-		if state == 0 {
-			if c != '[' { return output, endIndex, false }
-			state = 1
-			continue
-		}
-		
-		if state == 1  {
-			v, index, ok := parseValue(s[i:len(s)])
-			if !ok {
-				continue
-			} else {
-				output = append(output, v)
-				i = i + index - 1 
-				state = 2
-			}
-		}
-		
-		if state == 2 {
-			if c == ',' {
-				state = 1
-				continue
-			}
-			if c == ']' {
-				endIndex = i + 1
-				break
-			}
-		}
+func parseNumber(s string) (string, int, bool) {
+	allowedChars := "0123456789.-+eE"
+	index := 0
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') { index++ }
+	if strings.HasPrefix(s[index:len(s)], "inf") || strings.HasPrefix(s[index:len(s)], "nan") { return s[0:index + 3], index + 3, true }
+	for index < len(s) && strings.IndexByte(allowedChars, s[index]) >= 0 {
+		index++
 	}
-	
-	return output, endIndex, true
+	if index <= 0 { return "", 0, false }
+	return s[0:index], index, true
 }
 
 func parseString(s string) (string, int, bool) {
-	if len(s) <= 0 { return "", 0, false }
-	
-	index := 0
-	escape := false
-	output := ""
-	state := 0 // 0 = left, 1 = inside
-	i := -1
-	for _, c := range s {
-		i++
-		
-		if state == 0 {
-			if c != '"' { return "", 0, false }
-			state = 1
-			continue
-		}
-		
-		if state == 1 {
-			if escape {
-				if c == '0' {
-					output += "\x00"
-				} else if (c == 't') {
-					output += "\t"
-				} else if (c == 'n') {
-					output += "\n"
-				} else if (c == 'r') {
-					output += "\r"
-				} else if (c == '"') {
-					output += "\""
-				} else if (c == '\\') {
-					output += "\\"
-				} else {
-					return "", 0, false // Or panic?
-				}
-				escape = false
-				continue
-			}
-			
-			if c == '\\' {
-				escape = true
-				continue
-			} 
-			
-			if c == '"' && !escape {
-				index = i + 1
-				break
-			}
-			
-			output += string(c)
-		}
-	}
-		
-	return output, index, true	
+	var state parseState
+	return state.string(s, 0)
 }
 
 func (this Value) AsArray() []Value {
-	return this.asArray
+	if this.kind != kindArray || this.array == nil { return nil }
+	output := make([]Value, len(this.array))
+	copy(output, this.array)
+	return output
 }
 
 func (this Value) AsString() string {
-	return this.asString
+	if this.kind != kindString { return "" }
+	return this.text
 }
 
+// The integer accessors truncate the values that don't fit in their type.
+// Use Int(), Int8()... to check the range.
 func (this Value) AsInt() int {
-	return int(this.asInt)
+	return int(this.AsInt64())
 }
 
 func (this Value) AsInt8() int8 {
-	return int8(this.asInt)
+	return int8(this.AsInt64())
 }
 
 func (this Value) AsInt16() int16 {
-	return int16(this.asInt)
+	return int16(this.AsInt64())
 }
 
 func (this Value) AsInt32() int32 {
-	return int32(this.asInt)
+	return int32(this.AsInt64())
 }
 
 func (this Value) AsInt64() int64 {
-	return this.asInt
+	if this.kind != kindInt { return 0 }
+	return int64(this.scalar)
+}
+
+// Returns a new big.Int for any integer, whether it fits in an int64 or not
+func (this Value) AsBigInt() *big.Int {
+	if this.kind == kindInt { return big.NewInt(int64(this.scalar)) }
+	if this.kind != kindBigInt { return nil }
+	output, _ := new(big.Int).SetString(this.text, 10)
+	return output
 }
 
 func (this Value) AsFloat() float64 {
-	return this.asFloat
+	return this.AsFloat64()
 }
 
 func (this Value) AsFloat32() float32 {
-	return float32(this.asFloat)
+	return float32(this.AsFloat64())
 }
 
 func (this Value) AsFloat64() float64 {
-	return this.asFloat
+	if this.kind != kindFloat { return 0 }
+	return math.Float64frombits(this.scalar)
 }
 
 func (this Value) AsBool() bool {
-	return this.asBool	
+	return this.kind == kindBool && this.scalar != 0
 }
 
+// Returns the time of an offset date-time. Local dates and times are not
+// converted, see AsLocalDateTime().
 func (this Value) AsDate() time.Time {
-	return this.asDate
+	if this.kind != kindDate { return time.Time{} }
+	output := time.Unix(int64(this.scalar), int64(this.nsec))
+	if this.zone == 0 && this.text == "" { return output.UTC() }
+	return output.In(time.FixedZone(this.text, int(this.zone) * 60))
+}
+
+func (this *Value) setDate(v time.Time) {
+	name, offset := v.Zone()
+	this.kind = kindDate
+	this.scalar = uint64(v.Unix())
+	this.nsec = int32(v.Nanosecond())
+	this.zone = int16(offset / 60)
+	if name != "UTC" { this.text = name }
 }
 
-func (this *Node) createChildren() {
-	if this.Children != nil { return }
-	this.Children = make(map[string]*Node)
+// Returns the text of the value in the document it was parsed from, or the
+// TOML representation of the value if it wasn't parsed, if the parser
+// discarded the document, or if the value or one of its elements is sensitive.
+func (this Value) Raw() string {
+	if this.source == nil || this.IsSensitive() { return this.String() }
+	return this.source.text[this.offset:this.end]
 }
 
-func (this *Node) child(name string) (*Node, bool) {
-	if !this.hasChildren() { return nil, false }
-	node, ok := this.Children[name]
-	return node, ok
+// Nodes with up to this number of children find them without a map, which
+// saves its allocations in the sections that only have a few keys
+const maxChildrenWithoutMap = 8
+
+// Returns the index of the child in this.order, or -1
+func (this *Node) childIndex(name string) int {
+	for i, node := range this.order {
+		if node.name == name { return i }
+	}
+	return -1
+}
+
+// Returns the section or value with the given name, directly under this node
+func (this *Node) Child(name string) (*Node, bool) {
+	if this.children != nil {
+		node, ok := this.children[name]
+		return node, ok
+	}
+	if i := this.childIndex(name); i >= 0 { return this.order[i], true }
+	return nil, false
 }
 
 func (this *Node) setChild(name string, node *Node) {
-	this.createChildren()
-	this.Children[name] = node
+	node.name = name
 	node.parent = this
+	if _, exists := this.Child(name); exists {
+		this.order[this.childIndex(name)] = node
+		if this.children != nil { this.children[name] = node }
+		return
+	}
+	if this.order == nil { this.order = make([]*Node, 0, 4) }
+	this.order = append(this.order, node)
+	if this.children != nil {
+		this.children[name] = node
+	} else if len(this.order) > maxChildrenWithoutMap {
+		this.children = make(map[string]*Node, 2 * len(this.order))
+		for _, child := range this.order {
+			this.children[child.name] = child
+		}
+	}
+}
+
+func (this *Node) removeChild(name string) {
+	i := this.childIndex(name)
+	if i < 0 { return }
+	this.order = append(this.order[0:i:i], this.order[i + 1:len(this.order)]...)
+	if this.children != nil { delete(this.children, name) }
 }
 
 func (this *Node) hasChildren() bool {
-	return this.Children != nil
+	return len(this.order) > 0
+}
+
+// Returns the sections and values of this node, in declaration order
+func (this *Node) Children() []*Node {
+	output := make([]*Node, len(this.order))
+	copy(output, this.order)
+	return output
+}
+
+// Returns a deep copy of this node and its children. The copy has no parent.
+func (this *Node) Clone() *Node {
+	output := newNodePointer()
+	output.name = this.name
+	output.value = this.value
+	output.kind = this.kind
+	output.comment = this.comment
+	output.source = this.source
+	output.offset = this.offset
+	output.end = this.end
+	for _, node := range this.Children() {
+		output.setChild(node.name, node.Clone())
+	}
+	return output
 }
 
 func (this Value) String() string {
+	if this.flags & flagSensitive != 0 { return "\"" + redacted + "\"" }
 	if this.kind == kindString {
-		s := this.asString
+		s := this.text
+		s = strings.Replace(s, "\\", "\\\\", -1)
 		s = strings.Replace(s, "\n", "\\n", -1)
 		s = strings.Replace(s, "\x00", "\\0", -1)
 		s = strings.Replace(s, "\t", "\\t", -1)
 		s = strings.Replace(s, "\r", "\\r", -1)
 		s = strings.Replace(s, "\"", "\\\"", -1)
-		s = strings.Replace(s, "\\", "\\\\", -1)
 		return "\"" + s + "\""
 	}
-	if this.kind == kindInt { return strconv.FormatInt(this.asInt, 10) }
-	if this.kind == kindFloat { return strconv.FormatFloat(this.asFloat, 'f', -1, 64); }
-	if this.kind == kindBool { if this.asBool { return "true" } else { return "false" } }
-	if this.kind == kindDate { return this.asDate.Format(time.RFC3339) }
+	if this.kind == kindInt { return strconv.FormatInt(this.AsInt64(), 10) }
+	if this.kind == kindBigInt { return this.text }
+	if this.kind == kindFloat && this.text != "" { return this.text }
+	if this.kind == kindFloat { return formatFloat(this.AsFloat64()) }
+	if this.kind == kindBool { if this.AsBool() { return "true" } else { return "false" } }
+	if isDateKind(this.kind) { return this.formatDate() }
 	if this.kind == kindArray {
-		array := this.asArray
+		array := this.array
 		output := ""
 		for i := 0; i < len(array); i++ {
 			if output != "" { output += ", " }
@@ -351,6 +382,15 @@ func (this Value) String() string {
 		}
 		return "[" + output + "]"
 	}
+	if this.kind == kindTable {
+		output := ""
+		for i := 0; i + 1 < len(this.array); i += 2 {
+			if output != "" { output += ", " }
+			output += this.array[i].text + " = " + this.array[i + 1].String()
+		}
+		if output == "" { return "{}" }
+		return "{ " + output + " }"
+	}
 	return "undefined"
 }
 
@@ -358,7 +398,7 @@ func (this *Node) String() string {
 	output := ""
 		
 	if (this.kind == kindRoot && this.hasChildren()) {
-		for _, node := range this.Children {
+		for _, node := range this.valuesFirst() {
 			output += node.String()
 			output += "\n"
 		}
@@ -368,7 +408,7 @@ func (this *Node) String() string {
 		output += "[" + this.FullName() + "]"
 		output += "\n"
 		if (this.hasChildren()) {
-			for _, node := range this.Children {
+			for _, node := range this.valuesFirst() {
 				output += node.String()
 			}
 		}
@@ -393,13 +433,57 @@ func (this *Node) FullName() string {
 	return output
 }
 
+func (this *Node) path() []string {
+	var output []string
+	for current := this; current != nil && current.kind != kindRoot; current = current.parent {
+		output = append([]string{current.name}, output...)
+	}
+	return output
+}
+
+// Returns the children in declaration order, values before sections, so that
+// values are printed under the right section header.
+func (this *Node) valuesFirst() []*Node {
+	var values, sections []*Node
+	for _, node := range this.Children() {
+		if node.kind == kindValue {
+			values = append(values, node)
+		} else {
+			sections = append(sections, node)
+		}
+	}
+	return append(values, sections...)
+}
+
+func (this *Node) sortedChildren() []*Node {
+	output := this.Children()
+	sort.Slice(output, func(i int, j int) bool { return output[i].name < output[j].name })
+	return output
+}
+
+func (this *Node) eachValue(fn func(node *Node)) {
+	for _, node := range this.sortedChildren() {
+		if node.kind == kindValue {
+			fn(node)
+		} else {
+			node.eachValue(fn)
+		}
+	}
+}
+
+// Returns the comment lines just above the key or section header, followed
+// by the comment at the end of the line, if any.
+func (this *Node) Comment() string {
+	return this.comment
+}
+
 func (this Document) String() string {
 	return this.root.String()
 }
 
 func newNodePointer() *Node {
 	output := new(Node) 
-	output.Children = nil
+	output.children = nil
 	output.parent = nil
 	return output;
 }
@@ -411,47 +495,24 @@ func newDocument() Document {
 	return output;
 }
 
-func (this Parser) parseKey(line string) (string, int) {
-	index := strings.Index(line, "=")
-	if index < 0 { return "", -1 }
-	return strings.Trim(line[0:index], " \t\n\r"), index
-}
-
-func (this *Node) loadValues() {
-	this.value, _, _ = parseValue(this.value.raw)
-	
-	for _, node := range this.Children {
-		node.loadValues()
-	}
-}
-
 func (this *Node) GetSection(path string) (*Node, bool) {
 	names := strings.Split(path, ".")
 	current := this
-	nameIndex := 0
-	
-	for {
-		for _, node := range current.Children {
-			 if node.kind != kindSection { continue } 
-			 if node.name == names[nameIndex] {
-			 	current = node
-			 	nameIndex++	
-			 	if nameIndex >= len(names) {
-			 		return current, true
-			 	}
-			 	break
-			 }
-		}
+
+	for _, name := range names {
+		node, ok := current.Child(name)
+		if !ok || node.kind != kindSection { return nil, false }
+		current = node
 	}
-	
-	return current, false
+
+	return current, true
 }
 
 func (this *Node) GetValue(path string) (Value, bool) {
 	var output Value
 	names := strings.Split(path, ".")
 	if len(names) == 1 {
-		node, ok := this.child(path)
+		node, ok := this.Child(path)
 		if !ok { return output, false }
 		return node.value, true
 	}
@@ -471,74 +532,49 @@ func (this Document) GetValue(path string) (Value, bool) {
 }
 
 func (this Parser) Parse(tomlString string) Document {
-	_ = fmt.Println
-	
-	output := newDocument()
-	
-	var currentValue *Node
-	currentValue = nil 
-	currentSection := output.root
-	lines := strings.Split(tomlString, "\n")
-	for i := 0; i < len(lines); i++ {
-		var line = strings.Trim(lines[i], " \t\n\r")
-		if len(line) == 0 { continue }
-		
-		// COMMENT
-		
-		if line[0] == '#' { continue }
-		
-		// SECTION
-		
-		if line[0] == '[' && line[len(line) - 1] == ']' {
-			currentValue = nil
-			path := line[1:len(line) - 1]
-			names := strings.Split(path, ".")
-			current := output.root
-			for j := 0; j < len(names); j++ {
-				name := names[j]
-				node, ok := current.child(name)
-				if !ok {
-					section := newNodePointer()
-					section.name = name
-					section.kind = kindSection
-					current.setChild(name, section)
-					current = section
-				} else {
-					current = node
-				}
-				currentSection = current
-			}
-			continue
-		}
-		
-		// VALUE
-		
-		key, index := this.parseKey(line)
-		if index < 0 {
-			if currentValue != nil {
-				currentValue.value.raw += line
-			} else {
-				panic("Invalid value: " + line)
-			}
-		} else {
-			node := newNodePointer()
-			node.name = key
-			node.value.raw = cleanRawValue(line[index + 1:len(line)])
-			node.kind = kindValue
-			currentSection.setChild(key, node)
-			currentValue = node
+	return this.mustParse(tomlString, "")
+}
+
+// Parses the document and returns it along with the errors found, as an
+// ErrorList. By default, parsing stops at the first error and the document
+// contains what was parsed before it. If AllErrors is set, the parser skips
+// the invalid line, key or section, resumes on the next line or section header
+// and reports every error.
+func (this Parser) TryParse(tomlString string) (Document, error) {
+	return this.tryParse(tomlString, "")
+}
+
+func (this Parser) tryParse(tomlString string, fileName string) (Document, error) {
+	return this.parse(tomlString, fileName)
+}
+
+// Panics on syntax errors and exceeded limits. Invalid values are kept as
+// undefined values and the last duplicate key is ignored.
+func (this Parser) mustParse(tomlString string, fileName string) Document {
+	this.AllErrors = true
+	output, err := this.parse(tomlString, fileName)
+	if errors, ok := err.(ErrorList); ok {
+		for _, err := range errors {
+			if err.Code != ErrInvalidValue && err.Code != ErrDuplicateKey { panic(err.Error()) }
 		}
+	} else if err != nil {
+		panic(err.Error())
 	}
-	
-	output.root.loadValues()
-		
 	return output
 }
 
 func (this Parser) ParseFile(tomlFilePath string) Document {
-	content, err := ioutil.ReadFile(tomlFilePath)
+	content, err := this.readFile(tomlFilePath)
 	if err != nil {
 		panic(err.Error())
 	}
-	return this.Parse(string(content))
+	return this.mustParse(content, tomlFilePath)
+}
+
+// Same as TryParse() for the content of a file. If the file can't be read, the
+// error is returned as is.
+func (this Parser) TryParseFile(tomlFilePath string) (Document, error) {
+	content, err := this.readFile(tomlFilePath)
+	if err != nil { return newDocument(), err }
+	return this.tryParse(content, tomlFilePath)
 }
@@ -0,0 +1,101 @@
+package toml
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A number of bytes, written like "512B", "10MiB" or "1.5GB"
+type ByteSize int64
+
+const (
+	Byte ByteSize = 1
+	KiB = 1024 * Byte
+	MiB = 1024 * KiB
+	GiB = 1024 * MiB
+	TiB = 1024 * GiB
+	PiB = 1024 * TiB
+	KB = 1000 * Byte
+	MB = 1000 * KB
+	GB = 1000 * MB
+	TB = 1000 * GB
+	PB = 1000 * TB
+)
+
+// Largest first, binary units before decimal ones, for String()
+var byteUnits = []struct {
+	name string
+	size ByteSize
+}{
+	{"PiB", PiB}, {"TiB", TiB}, {"GiB", GiB}, {"MiB", MiB}, {"KiB", KiB},
+	{"PB", PB}, {"TB", TB}, {"GB", GB}, {"MB", MB}, {"KB", KB},
+	{"B", Byte},
+}
+
+// Returns the size with the largest unit that divides it exactly, eg. "10MiB"
+func (this ByteSize) String() string {
+	for _, unit := range byteUnits {
+		if this != 0 && this % unit.size == 0 { return strconv.FormatInt(int64(this / unit.size), 10) + unit.name }
+	}
+	return "0B"
+}
+
+// Parses a size such as "10MiB", "1.5 GB" or "512". Units are case insensitive,
+// KiB, MiB... are powers of 1024, and KB, MB... powers of 1000. A number
+// without a unit is a number of bytes.
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	index := strings.IndexFunc(s, func(c rune) bool { return (c < '0' || c > '9') && c != '.' && c != '-' && c != '+' })
+	number, unitName := s, "B"
+	if index >= 0 { number, unitName = s[0:index], strings.TrimSpace(s[index:len(s)]) }
+
+	unit := ByteSize(0)
+	for _, u := range byteUnits {
+		if strings.EqualFold(u.name, unitName) { unit = u.size }
+	}
+	if unit == 0 || number == "" { return 0, fmt.Errorf("toml: invalid byte size: %q", s) }
+
+	if n, err := strconv.ParseInt(number, 10, 64); err == nil {
+		if n > math.MaxInt64 / int64(unit) || n < math.MinInt64 / int64(unit) { return 0, &RangeError{s, "toml.ByteSize"} }
+		return ByteSize(n) * unit, nil
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil { return 0, fmt.Errorf("toml: invalid byte size: %q", s) }
+	f *= float64(unit)
+	if f >= math.MaxInt64 || f <= math.MinInt64 { return 0, &RangeError{s, "toml.ByteSize"} }
+	return ByteSize(math.Round(f)), nil
+}
+
+// Returns the duration of a string written like "1h30m" or "250ms", see
+// time.ParseDuration(), or an error if the value is not such a string
+func (this Value) Duration() (time.Duration, error) {
+	if this.kind != kindString { return 0, fmt.Errorf("toml: expected duration string, got %s", this.kind) }
+	output, err := time.ParseDuration(this.text)
+	if err != nil { return 0, fmt.Errorf("toml: invalid duration: %s", this.redact(strconv.Quote(this.text))) }
+	return output, nil
+}
+
+// Returns the size of a string written like "10MiB", see ParseByteSize(), or
+// of an integer number of bytes
+func (this Value) ByteSize() (ByteSize, error) {
+	if this.kind == kindInt { return ByteSize(this.AsInt64()), nil }
+	if this.kind != kindString { return 0, fmt.Errorf("toml: expected byte size, got %s", this.kind) }
+	output, err := ParseByteSize(this.text)
+	if err != nil && this.flags & flagSensitive != 0 { return 0, fmt.Errorf("toml: invalid byte size: %s", this) }
+	return output, err
+}
+
+// Returns 0 if the value is not a valid duration
+func (this Value) AsDuration() time.Duration {
+	output, _ := this.Duration()
+	return output
+}
+
+// Returns 0 if the value is not a valid byte size
+func (this Value) AsByteSize() ByteSize {
+	output, _ := this.ByteSize()
+	return output
+}
@@ -0,0 +1,96 @@
+package toml
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Called for each section, key and array element, and for each key of the
+// inline tables and of the tables of an [[array of tables]]. For sections,
+// value is nil. For array elements and table keys, node is the key that holds
+// the array or table, and the last component of the path is the element index
+// or the name of the key.
+type WalkFunc func(path []string, node *Node, value *Value) error
+
+// Return SkipSubtree from a pre-order function to skip the children of a
+// section or the elements of an array or table, and StopWalk to end the walk early
+// without error. Any other error ends the walk and is returned by Walk().
+var SkipSubtree = errors.New("skip subtree")
+var StopWalk = errors.New("stop walk")
+
+type Visitor struct {
+	Pre WalkFunc // Called before the children or elements, if any
+	Post WalkFunc // Called after the children or elements, if any
+}
+
+// Calls fn for every section, key and array element of the document, in
+// declaration order, parents before children.
+func (this Document) Walk(fn WalkFunc) error {
+	return this.root.Visit(Visitor{Pre: fn})
+}
+
+func (this Document) Visit(visitor Visitor) error {
+	return this.root.Visit(visitor)
+}
+
+// Same as Document.Walk() for this node and everything below it
+func (this *Node) Walk(fn WalkFunc) error {
+	return this.Visit(Visitor{Pre: fn})
+}
+
+func (this *Node) Visit(visitor Visitor) error {
+	err := this.visit(this.path(), visitor)
+	if err == StopWalk { return nil }
+	return err
+}
+
+func (this *Node) visit(path []string, visitor Visitor) error {
+	if this.kind == kindValue { return visitValue(path, this, this.value, visitor) }
+
+	if this.kind != kindRoot {
+		if err := visitor.pre(path, this, nil); err == SkipSubtree {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	for _, node := range this.Children() {
+		if err := node.visit(append(path[0:len(path):len(path)], node.name), visitor); err != nil { return err }
+	}
+
+	if this.kind == kindRoot { return nil }
+	return visitor.post(path, this, nil)
+}
+
+func visitValue(path []string, node *Node, value Value, visitor Visitor) error {
+	if err := visitor.pre(path, node, &value); err == SkipSubtree {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if value.kind == kindTable {
+		for i := 0; i + 1 < len(value.array); i += 2 {
+			if err := visitValue(append(path[0:len(path):len(path)], value.array[i].text), node, value.array[i + 1], visitor); err != nil { return err }
+		}
+	} else {
+		for i, element := range value.array {
+			if err := visitValue(append(path[0:len(path):len(path)], strconv.Itoa(i)), node, element, visitor); err != nil { return err }
+		}
+	}
+
+	return visitor.post(path, node, &value)
+}
+
+func (this Visitor) pre(path []string, node *Node, value *Value) error {
+	if this.Pre == nil { return nil }
+	return this.Pre(path, node, value)
+}
+
+func (this Visitor) post(path []string, node *Node, value *Value) error {
+	if this.Post == nil { return nil }
+	err := this.Post(path, node, value)
+	if err == SkipSubtree { return nil }
+	return err
+}
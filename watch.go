@@ -0,0 +1,121 @@
+package toml
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type WatchEvent struct {
+	Document Document // The new document or, if Err is set, the last good one
+	Changed []string // Key paths of the values that were added, removed or modified
+	Err error // Set if the file could not be read or parsed
+}
+
+// Watches a file by polling it, and parses it again whenever its content
+// changes. The current document can be read from any goroutine.
+type Watcher struct {
+	parser Parser
+	path string
+	interval time.Duration
+	content string
+	readErr string
+	document atomic.Value
+	mutex sync.Mutex
+	subscribers []func(WatchEvent)
+	stop chan bool
+	done chan bool
+	closeOnce sync.Once
+}
+
+// Parses the file and starts watching it. The file is checked for changes
+// every interval (one second if zero). Like ParseFile(), files larger than
+// MaxInputSize are not read.
+func (this Parser) WatchFile(tomlFilePath string, interval time.Duration) (*Watcher, error) {
+	if interval <= 0 { interval = time.Second }
+	content, err := this.readFile(tomlFilePath)
+	if err != nil { return nil, err }
+	doc, err := this.tryParse(content, tomlFilePath)
+	if err != nil { return nil, err }
+
+	output := &Watcher{
+		parser: this,
+		path: tomlFilePath,
+		interval: interval,
+		content: content,
+		stop: make(chan bool),
+		done: make(chan bool),
+	}
+	output.document.Store(doc)
+	go output.run()
+	return output, nil
+}
+
+// Returns the last document that was successfully parsed
+func (this *Watcher) Document() Document {
+	return this.document.Load().(Document)
+}
+
+// Registers a function to call after each reload attempt. Functions are
+// called in order from the watcher goroutine.
+func (this *Watcher) Subscribe(fn func(WatchEvent)) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.subscribers = append(this.subscribers, fn)
+}
+
+// Stops watching the file. No event is published after Close() returns, and
+// further calls do nothing.
+func (this *Watcher) Close() {
+	this.closeOnce.Do(func() { close(this.stop) })
+	<-this.done
+}
+
+func (this *Watcher) run() {
+	defer close(this.done)
+	ticker := time.NewTicker(this.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-this.stop:
+			return
+		case <-ticker.C:
+			this.check()
+		}
+	}
+}
+
+func (this *Watcher) check() {
+	content, err := this.parser.readFile(this.path)
+	if err != nil {
+		if err.Error() == this.readErr { return }
+		this.readErr = err.Error()
+	} else {
+		this.readErr = ""
+		if content == this.content { return }
+	}
+
+	var event WatchEvent
+	previous := this.Document()
+	if err == nil {
+		this.content = content
+		event.Document, err = this.parser.tryParse(content, this.path)
+	}
+
+	if err != nil {
+		event.Document = previous
+		event.Err = err
+	} else {
+		for _, change := range Diff(previous, event.Document) {
+			event.Changed = append(event.Changed, change.Path)
+		}
+		this.document.Store(event.Document)
+	}
+
+	this.mutex.Lock()
+	subscribers := this.subscribers
+	this.mutex.Unlock()
+	for _, fn := range subscribers {
+		fn(event)
+	}
+}